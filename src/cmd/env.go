@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	goferenv "github.com/Azmekk/gofer/env"
+	"github.com/Azmekk/gofer/executor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	envJSON        bool
+	envExport      bool
+	envShowSecrets bool
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env [task] [args...]",
+	Short: "Show the environment a task's commands run with",
+	Long: `Show the environment a task's commands run with: the host environment, the
+env files (including the active profile's), and the task's env_file and env,
+each variable annotated with where its value came from. The env of the task's
+steps is listed after it. Without a task, shows the environment of the root
+config alone.
+
+The task's vars are resolved, so its sh vars run, but its deps and steps don't.
+Values of env_secret variables, and values that look like secrets, are masked
+unless --show-secrets is given.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runEnv,
+}
+
+func init() {
+	envCmd.Flags().StringArrayVarP(&paramFlags, "param", "p", nil, "task parameter in key=value format")
+	envCmd.Flags().BoolVar(&envJSON, "json", false, "print the environment as JSON")
+	envCmd.Flags().BoolVar(&envExport, "export", false, "print the environment as shell export statements")
+	envCmd.Flags().BoolVar(&envShowSecrets, "show-secrets", false, "don't mask secret values")
+	envCmd.Flags().BoolVar(&noInput, "no-input", false, "never prompt for missing parameters")
+	envCmd.MarkFlagsMutuallyExclusive("json", "export")
+}
+
+// envEntry is a variable as printed by gofer env.
+type envEntry struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Masked bool   `json:"masked,omitempty"`
+}
+
+type stepEnvEntry struct {
+	Step string     `json:"step"`
+	Env  []envEntry `json:"env"`
+}
+
+type envReport struct {
+	Task    string         `json:"task,omitempty"`
+	Profile string         `json:"profile,omitempty"`
+	Env     []envEntry     `json:"env"`
+	Steps   []stepEnvEntry `json:"steps,omitempty"`
+}
+
+func runEnv(cmd *cobra.Command, args []string) error {
+	cfg, raw, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if errs := validateConfig(cfg, raw); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "validation error: %s\n", e)
+		}
+		return fmt.Errorf("config validation failed")
+	}
+
+	var taskRef string
+	params := map[string]string{}
+	if len(args) > 0 {
+		taskRef = args[0]
+		if params, err = taskParams(cfg, taskRef, args[1:]); err != nil {
+			return err
+		}
+	} else if len(paramFlags) > 0 {
+		return fmt.Errorf("--param needs a task")
+	}
+
+	exec := executor.New(cfg, os.Environ(), params)
+	if !noInput && canPrompt() {
+		exec.Prompt = promptParam
+	}
+
+	ctx, stop := executor.NotifyContext(context.Background())
+	defer stop()
+	vars, steps, err := exec.TaskEnv(ctx, taskRef)
+	if err != nil {
+		return err
+	}
+
+	report := envReport{Task: taskRef, Profile: cfg.Profile, Env: envEntries(vars)}
+	for _, s := range steps {
+		report.Steps = append(report.Steps, stepEnvEntry{Step: s.Step, Env: envEntries(s.Env)})
+	}
+
+	switch {
+	case envJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case envExport:
+		printEnvExport(report)
+		return nil
+	default:
+		printEnvReport(report)
+		return nil
+	}
+}
+
+// envEntries converts vars for printing, masking the values that are marked
+// secret or look like secrets unless --show-secrets is set.
+func envEntries(vars []executor.EnvVar) []envEntry {
+	entries := make([]envEntry, len(vars))
+	for i, v := range vars {
+		entries[i] = envEntry{Name: v.Name, Value: v.Value, Source: v.Source}
+		if !envShowSecrets && (v.Secret || goferenv.LooksSecret(v.Name, v.Value)) {
+			entries[i].Value = "***"
+			entries[i].Masked = true
+		}
+	}
+	return entries
+}
+
+func printEnvReport(r envReport) {
+	if r.Profile != "" {
+		fmt.Printf("# profile %s\n", r.Profile)
+	}
+	printEnvEntries(r.Env)
+	for _, s := range r.Steps {
+		fmt.Printf("\n# step %q sets:\n", s.Step)
+		printEnvEntries(s.Env)
+	}
+}
+
+// maxEnvColumn caps the width the sources are aligned at, so one long value
+// (PATH, LS_COLORS) doesn't push every source off screen.
+const maxEnvColumn = 48
+
+func printEnvEntries(entries []envEntry) {
+	lines := make([]string, len(entries))
+	width := 0
+	for i, e := range entries {
+		value := e.Value
+		if strings.ContainsFunc(value, func(r rune) bool { return !strconv.IsPrint(r) }) {
+			value = strconv.Quote(value)
+		}
+		lines[i] = e.Name + "=" + value
+		if n := utf8.RuneCountInString(lines[i]); n <= maxEnvColumn {
+			width = max(width, n)
+		}
+	}
+	for i, e := range entries {
+		pad := max(width-utf8.RuneCountInString(lines[i]), 0) + 2
+		fmt.Printf("%s%s# %s\n", lines[i], strings.Repeat(" ", pad), e.Source)
+	}
+}
+
+// printEnvExport prints the task's environment as POSIX shell export
+// statements. Masked variables, and those whose names the shell can't set,
+// are left commented out. Step env isn't included.
+func printEnvExport(r envReport) {
+	for _, e := range r.Env {
+		if e.Masked {
+			fmt.Printf("# export %s=*** (masked, use --show-secrets)\n", e.Name)
+			continue
+		}
+		if !shellName.MatchString(e.Name) {
+			fmt.Printf("# %s is not a valid shell variable name\n", e.Name)
+			continue
+		}
+		fmt.Printf("export %s=%s\n", e.Name, shQuote(e.Value))
+	}
+}
+
+var shellName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shQuote quotes s for a POSIX shell, whatever the OS gofer runs on.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Azmekk/gofer/executor"
+	"github.com/spf13/cobra"
+)
+
+var funcsCmd = &cobra.Command{
+	Use:   "funcs",
+	Short: "List the functions available in templates",
+	RunE:  runFuncs,
+}
+
+func runFuncs(cmd *cobra.Command, args []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, f := range executor.FuncDocs {
+		fmt.Fprintf(w, "  %s\t%s\n", f.Name, f.Desc)
+		fmt.Fprintf(w, "  \t%s\n", f.Usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Templates can also use the params, vars and captures of their task, the built-ins")
+	fmt.Fprintln(w, "{{.OS}}, {{.ARCH}}, {{.ENV.NAME}} and {{.ARGS}}, and Go's own template functions")
+	fmt.Fprintln(w, "(eq, ne, and, or, not, len, index, printf, ...).")
+	return w.Flush()
+}
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/Azmekk/gofer/config"
-	goferenv "github.com/Azmekk/gofer/env"
 	"github.com/Azmekk/gofer/executor"
 	"github.com/Azmekk/gofer/schema"
 	"github.com/spf13/cobra"
@@ -16,6 +20,12 @@ var (
 	Version    = "dev"
 	configPath string
 	paramFlags []string
+	forceFlag  bool
+	graceFlag  time.Duration
+	failFast   bool
+	jobsFlag   int
+	noInput    bool
+	profile    string
 )
 
 var rootCmd = &cobra.Command{
@@ -39,15 +49,26 @@ var rootCmd = &cobra.Command{
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "gofer.json", "path or URL to config file")
 	rootCmd.PersistentFlags().Bool("update", false, "update gofer to the latest version")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "profile to apply (default $GOFER_PROFILE)")
 	rootCmd.Flags().StringArrayVarP(&paramFlags, "param", "p", nil, "task parameter in key=value format")
+	rootCmd.Flags().DurationVar(&graceFlag, "grace-period", executor.DefaultGracePeriod, "how long interrupted commands get to exit before being killed")
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", false, "cancel the rest of a concurrent block as soon as one step fails")
+	rootCmd.Flags().IntVarP(&jobsFlag, "jobs", "j", 0, "maximum number of commands to run at once (0 = unlimited)")
+	rootCmd.Flags().BoolVar(&noInput, "no-input", false, "never prompt for missing parameters")
+	rootCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "run tasks even if their sources and generates are up to date")
 
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(funcsCmd)
+	rootCmd.AddCommand(envCmd)
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		if errors.Is(err, executor.ErrInterrupted) {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
@@ -59,46 +80,139 @@ func runTask(cmd *cobra.Command, args []string) error {
 
 	taskRef := args[0]
 	positionalArgs := args[1:]
+	var passthrough []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		split := max(dash, 1)
+		positionalArgs, passthrough = args[1:split], args[split:]
+	}
 
-	cfg, raw, err := config.LoadAuto(configPath)
+	cfg, raw, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
-	if errs := schema.Validate(raw); len(errs) > 0 {
+	if errs := validateConfig(cfg, raw); len(errs) > 0 {
 		for _, e := range errs {
 			fmt.Fprintf(os.Stderr, "validation error: %s\n", e)
 		}
 		return fmt.Errorf("config validation failed")
 	}
 
-	task, err := cfg.ResolveTask(taskRef)
+	params, err := taskParams(cfg, taskRef, positionalArgs)
 	if err != nil {
 		return err
 	}
 
-	params := make(map[string]string)
+	exec := executor.New(cfg, os.Environ(), params)
+	exec.Force = forceFlag
+	exec.GracePeriod = graceFlag
+	exec.FailFast = failFast
+	exec.Jobs = jobsFlag
+	exec.Args = passthrough
+	if !noInput && canPrompt() {
+		exec.Prompt = promptParam
+	}
 
-	for i, arg := range positionalArgs {
-		if i < len(task.Params) {
-			params[task.Params[i].Name] = arg
-		}
+	ctx, stop := executor.NotifyContext(context.Background())
+	defer stop()
+	return exec.RunTask(ctx, taskRef)
+}
+
+// taskParams collects the params of task ref from its positional arguments
+// and --param flags, and validates them.
+func taskParams(cfg *config.GoferConfig, ref string, positionalArgs []string) (map[string]string, error) {
+	task, err := cfg.ResolveTask(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := executor.PositionalParams(ref, task, positionalArgs)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, pf := range paramFlags {
 		key, value, ok := strings.Cut(pf, "=")
 		if !ok {
-			return fmt.Errorf("invalid param format %q: expected key=value", pf)
+			return nil, fmt.Errorf("invalid param format %q: expected key=value", pf)
 		}
 		params[key] = value
 	}
 
-	envVars, err := goferenv.LoadEnvFile(cfg.EnvFile)
+	if err := validateParams(ref, task, params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// loadConfig loads the config at --config and applies the profile chosen
+// with --profile or GOFER_PROFILE, if any.
+func loadConfig() (*config.GoferConfig, []byte, error) {
+	cfg, raw, err := config.LoadAuto(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to load env file: %w", err)
+		return nil, nil, err
+	}
+	if name := activeProfile(cfg); name != "" {
+		if err := cfg.ApplyProfile(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cfg, raw, nil
+}
+
+// activeProfile is the profile named by --profile, or else GOFER_PROFILE.
+// The variable is typically exported for a whole shell, so configs that
+// define no profiles at all ignore it; any other config must define it.
+func activeProfile(cfg *config.GoferConfig) string {
+	if profile != "" {
+		return profile
+	}
+	if len(cfg.Profiles) == 0 {
+		return ""
+	}
+	return os.Getenv("GOFER_PROFILE")
+}
+
+// validateParams checks the values given on the command line (or defaulted)
+// for the task's declared params, reporting every invalid or undeclared one
+// at once. Missing required params are left to the executor.
+func validateParams(ref string, task *config.Task, params map[string]string) error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(params)) {
+		if !slices.ContainsFunc(task.Params, func(p config.Param) bool { return p.Name == name }) {
+			errs = append(errs, fmt.Errorf("task %q has no parameter %q", ref, name))
+		}
+	}
+	for _, p := range task.Params {
+		value, ok := params[p.Name]
+		if !ok {
+			if p.Default == nil {
+				continue
+			}
+			value = *p.Default
+		}
+		validate := p.Validate
+		if p.Variadic {
+			validate = func(value string) error {
+				_, err := executor.VariadicArgs(p, value)
+				return err
+			}
+		}
+		if err := validate(value); err != nil {
+			errs = append(errs, fmt.Errorf("task %q: %w", ref, err))
+		}
 	}
-	env := goferenv.BuildEnv(envVars)
+	return errors.Join(errs...)
+}
 
-	exec := executor.New(cfg, env, params)
-	return exec.RunTask(taskRef)
+// validateConfig validates the raw JSON of cfg and of every config it
+// includes. Errors from included configs are prefixed with their namespace.
+func validateConfig(cfg *config.GoferConfig, raw []byte) []error {
+	errs := schema.Validate(raw)
+	for _, ns := range cfg.Namespaces() {
+		for _, err := range validateConfig(cfg.Included[ns], cfg.Included[ns].Raw) {
+			errs = append(errs, fmt.Errorf("include %q: %w", ns, err))
+		}
+	}
+	return errs
 }
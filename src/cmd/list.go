@@ -16,11 +16,15 @@ var listCmd = &cobra.Command{
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	cfg, _, err := config.LoadAuto(configPath)
+	cfg, _, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
+	if len(cfg.Profiles) > 0 {
+		printProfile(cfg)
+	}
+
 	// Partition tasks by group
 	ungrouped := make(map[string]config.Task)
 	grouped := make(map[string]map[string]config.Task)
@@ -40,7 +44,7 @@ func runList(cmd *cobra.Command, args []string) error {
 	if len(ungrouped) > 0 {
 		names := sortedKeys(ungrouped)
 		for _, name := range names {
-			fmt.Printf("  %s%s - %s\n", name, formatParams(ungrouped[name].Params), ungrouped[name].Desc)
+			printTask(name, ungrouped[name])
 		}
 		if len(grouped) > 0 {
 			fmt.Println()
@@ -58,24 +62,68 @@ func runList(cmd *cobra.Command, args []string) error {
 		fmt.Printf("%s:\n", gName)
 		names := sortedKeys(grouped[gName])
 		for _, name := range names {
-			task := grouped[gName][name]
-			fmt.Printf("  %s%s - %s\n", name, formatParams(task.Params), task.Desc)
+			printTask(name, grouped[gName][name])
 		}
 		if i < len(groupNames)-1 {
 			fmt.Println()
 		}
 	}
 
+	// Print included tasks, one section per namespace
+	printIncludes(cfg, "", len(cfg.Tasks) > 0)
+
 	return nil
 }
 
+// printProfile prints which of the config's profiles is active.
+func printProfile(cfg *config.GoferConfig) {
+	active := cfg.Profile
+	if active == "" {
+		active = "none"
+	}
+	fmt.Printf("Profile: %s (available: %s)\n\n", active, strings.Join(cfg.ProfileNames(), ", "))
+}
+
+// printIncludes lists the tasks of every config included by cfg under a
+// header naming the namespace, recursing into nested includes.
+func printIncludes(cfg *config.GoferConfig, parent string, needSep bool) {
+	for _, ns := range cfg.Namespaces() {
+		inc := cfg.Included[ns]
+		qualified := config.Qualify(parent, ns)
+
+		if needSep {
+			fmt.Println()
+		}
+		needSep = true
+
+		fmt.Printf("%s (%s):\n", qualified, cfg.Includes[ns])
+		for _, name := range sortedKeys(inc.Tasks) {
+			printTask(config.Qualify(qualified, name), inc.Tasks[name])
+		}
+
+		printIncludes(inc, qualified, true)
+	}
+}
+
+// printTask prints a task's line followed by one indented line per
+// described param.
+func printTask(name string, task config.Task) {
+	fmt.Printf("  %s%s - %s\n", name, formatParams(task.Params), task.Desc)
+	for _, p := range task.Params {
+		if p.Desc != "" {
+			fmt.Printf("      %s: %s\n", p.Name, p.Desc)
+		}
+	}
+}
+
 func formatParams(params []config.Param) string {
 	var hints []string
 	for _, p := range params {
+		name := p.Name + paramType(p)
 		if p.Default != nil {
-			hints = append(hints, fmt.Sprintf("%s=%s", p.Name, *p.Default))
+			hints = append(hints, fmt.Sprintf("%s=%s", name, *p.Default))
 		} else {
-			hints = append(hints, fmt.Sprintf("<%s>", p.Name))
+			hints = append(hints, fmt.Sprintf("<%s>", name))
 		}
 	}
 	if len(hints) > 0 {
@@ -84,6 +132,18 @@ func formatParams(params []config.Param) string {
 	return ""
 }
 
+// paramType is the ":type" suffix shown after a param's name: its choices
+// if it has any, otherwise its type unless that is a plain string.
+func paramType(p config.Param) string {
+	switch {
+	case len(p.Choices) > 0:
+		return ":" + strings.Join(p.Choices, "|")
+	case p.Type != "" && p.Type != "string":
+		return ":" + p.Type
+	}
+	return ""
+}
+
 func sortedKeys(m map[string]config.Task) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Azmekk/gofer/config"
+	"github.com/mattn/go-isatty"
+)
+
+// canPrompt reports whether missing params can be asked for on the terminal.
+func canPrompt() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// promptParam asks for the value of a required param of task ref on the
+// terminal, repeating the question until the answer is valid. Secret params
+// are read without echo.
+func promptParam(ctx context.Context, ref string, p config.Param) (string, error) {
+	for {
+		fmt.Fprint(os.Stderr, promptText(ref, p))
+
+		line, err := readAnswer(ctx, p.Secret)
+		if p.Secret {
+			fmt.Fprintln(os.Stderr)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		value := strings.TrimRight(line, "\r\n")
+		if value == "" {
+			if p.Default != nil {
+				return *p.Default, nil
+			}
+			fmt.Fprintln(os.Stderr, "  a value is required")
+			continue
+		}
+		if err := p.Validate(value); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s\n", err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// promptText is the question for p, e.g.
+// `deploy: env - target environment [dev|prod] (default dev): `.
+func promptText(ref string, p config.Param) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", ref, p.Name)
+	if p.Desc != "" {
+		fmt.Fprintf(&b, " - %s", p.Desc)
+	}
+	switch {
+	case len(p.Choices) > 0:
+		fmt.Fprintf(&b, " [%s]", strings.Join(p.Choices, "|"))
+	case p.Type != "" && p.Type != "string":
+		fmt.Fprintf(&b, " [%s]", p.Type)
+	}
+	if p.Default != nil {
+		fmt.Fprintf(&b, " (default %s)", *p.Default)
+	}
+	b.WriteString(": ")
+	return b.String()
+}
+
+// readAnswer reads a line from stdin, giving up when ctx is cancelled (the
+// read itself is left behind; gofer is about to exit anyway).
+func readAnswer(ctx context.Context, secret bool) (string, error) {
+	if secret {
+		restore, err := disableEcho(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	type result struct {
+		line string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		line, err := stdinReader.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		ch <- result{line, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.line, r.err
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	}
+}
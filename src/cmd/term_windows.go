@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// disableEcho turns off echoing of typed characters on the console f and
+// returns a function that restores the previous mode.
+func disableEcho(f *os.File) (restore func(), err error) {
+	h := windows.Handle(f.Fd())
+	var old uint32
+	if err := windows.GetConsoleMode(h, &old); err != nil {
+		return nil, err
+	}
+	mode := old&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_LINE_INPUT | windows.ENABLE_PROCESSED_INPUT
+	if err := windows.SetConsoleMode(h, mode); err != nil {
+		return nil, err
+	}
+	return func() { windows.SetConsoleMode(h, old) }, nil
+}
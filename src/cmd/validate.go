@@ -41,6 +41,14 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	}
 
 	errs := schema.Validate(data)
+	if len(errs) == 0 {
+		// Included configs are only reachable by loading the root one.
+		cfg, _, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		errs = validateConfig(cfg, data)
+	}
 	if len(errs) == 0 {
 		fmt.Println("Configuration is valid.")
 		return nil
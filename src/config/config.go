@@ -5,96 +5,378 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 )
 
-type Param struct {
-	Name    string  `json:"name"`
-	Default *string `json:"default,omitempty"`
+// Retry configures how often a failed step is re-run. Delay is a duration
+// string waited before the first retry; each later delay is multiplied by
+// Backoff.
+type Retry struct {
+	Count   int     `json:"count"`
+	Delay   string  `json:"delay,omitempty"`
+	Backoff float64 `json:"backoff,omitempty"`
 }
 
 type Step struct {
-	Name       string `json:"name,omitempty"`
-	Cmd        string `json:"cmd,omitempty"`
-	Ref        string `json:"ref,omitempty"`
-	Concurrent []Step `json:"concurrent,omitempty"`
-	OS         string `json:"os,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Cmd         string `json:"cmd,omitempty"`
+	Ref         string `json:"ref,omitempty"`
+	Concurrent  []Step `json:"concurrent,omitempty"`
+	FailFast    bool   `json:"fail_fast,omitempty"`
+	MaxParallel int    `json:"max_parallel,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`
+	Retry       *Retry `json:"retry,omitempty"`
+	IgnoreError bool   `json:"ignore_error,omitempty"`
+	// Capture stores the trimmed stdout of a cmd step under this name for
+	// later steps and referenced tasks; CaptureExit stores its exit code
+	// and makes a non-zero exit not fail the step. Silent stops stdout from
+	// being shown.
+	Capture     string `json:"capture,omitempty"`
+	CaptureExit string `json:"capture_exit,omitempty"`
+	Silent      bool   `json:"silent,omitempty"`
+	// Dir is the directory a cmd step (or every step of a concurrent
+	// block) runs in, relative to the config file.
+	Dir string `json:"dir,omitempty"`
+	// Env values are templates set on top of the task's environment for
+	// this step, including the task a ref step runs.
+	Env map[string]string `json:"env,omitempty"`
+	// With sets params of the task a ref step runs. Values are templates
+	// resolved against the calling task's params.
+	With map[string]string `json:"with,omitempty"`
+	// If is a template; the step is skipped when it renders to "", "false"
+	// or "0". IfCmd is a shell command; the step is skipped when it exits
+	// with a non-zero status.
+	If    string `json:"if,omitempty"`
+	IfCmd string `json:"if_cmd,omitempty"`
+	OS    string `json:"os,omitempty"`
 }
 
 type Task struct {
-	Desc   string  `json:"desc"`
-	Group  string  `json:"group,omitempty"`
-	Params []Param `json:"params,omitempty"`
-	Steps  []Step  `json:"steps"`
+	Desc      string   `json:"desc"`
+	Group     string   `json:"group,omitempty"`
+	Params    []Param  `json:"params,omitempty"`
+	Deps      []string `json:"deps,omitempty"`
+	Sources   []string `json:"sources,omitempty"`
+	Generates []string `json:"generates,omitempty"`
+	// Dir is the directory the task's commands run in, relative to the
+	// config file.
+	Dir string `json:"dir,omitempty"`
+	// EnvFile and Env are layered, in that order, on top of the config's
+	// environment for the task's commands and the tasks its ref steps run.
+	// EnvFile is relative to the config file; Env values are templates.
+	EnvFile EnvFiles          `json:"env_file,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// EnvRequired lists variables that must be set and non-empty, counting
+	// the task's Env, before its steps run. It adds to the config's
+	// EnvRequired.
+	EnvRequired []string `json:"env_required,omitempty"`
+	// EnvClear starts the task from an empty environment instead of the
+	// host's, keeping only host variables matching an EnvAllow pattern.
+	// Env files and env values still apply.
+	EnvClear bool     `json:"env_clear,omitempty"`
+	EnvAllow []string `json:"env_allow,omitempty"`
+	// EnvSecret lists patterns (path.Match syntax) of variables whose
+	// values are redacted from the task's output, on top of the config's
+	// EnvSecret.
+	EnvSecret []string `json:"env_secret,omitempty"`
+	// Vars are evaluated before the task's steps run, on top of the
+	// config's own vars.
+	Vars map[string]Var `json:"vars,omitempty"`
+	// ContinueOnError keeps running the remaining steps after one fails; the
+	// task still fails at the end with all step errors joined.
+	ContinueOnError bool   `json:"continue_on_error,omitempty"`
+	Steps           []Step `json:"steps"`
+	// Finally steps run after Steps whether they succeeded, failed or were
+	// interrupted.
+	Finally []Step `json:"finally,omitempty"`
 }
 
+// Escape modes for GoferConfig.Escape.
+const (
+	// EscapeNone interpolates template values into commands as they are.
+	EscapeNone = "none"
+	// EscapeAuto quotes every interpolated value for the target shell.
+	EscapeAuto = "auto"
+)
+
+// Values of GoferConfig.EnvPrecedence and GoferConfig.EnvPrecedenceKeys.
+const (
+	// EnvPrecedenceFile lets env file values override the host's.
+	EnvPrecedenceFile = "file"
+	// EnvPrecedenceHost keeps host values over env file ones.
+	EnvPrecedenceHost = "host"
+)
+
+// DefaultEnvFile is the env file of a config that doesn't set env_file. It
+// is optional.
+const DefaultEnvFile = "?.env.gofer"
+
 type GoferConfig struct {
-	EnvFile string          `json:"env_file,omitempty"`
-	Tasks   map[string]Task `json:"tasks"`
+	EnvFile  EnvFiles          `json:"env_file,omitempty"`
+	Includes map[string]string `json:"includes,omitempty"`
+	Vars     map[string]Var    `json:"vars,omitempty"`
+	// EnvPrecedence decides whether env file values (EnvPrecedenceFile, the
+	// default) or host values (EnvPrecedenceHost) win when both set a
+	// variable. EnvPrecedenceKeys overrides it for single variables.
+	EnvPrecedence     string            `json:"env_precedence,omitempty"`
+	EnvPrecedenceKeys map[string]string `json:"env_precedence_keys,omitempty"`
+	// EnvRequired lists variables that must be set and non-empty before
+	// the steps of any of the config's tasks run.
+	EnvRequired []string `json:"env_required,omitempty"`
+	// EnvSecret lists patterns (path.Match syntax) of variables whose
+	// values are redacted from the output of the config's tasks.
+	EnvSecret []string `json:"env_secret,omitempty"`
+	// Profiles are named settings selected with ApplyProfile.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// Escape is EscapeNone (the default) or EscapeAuto and applies to the
+	// commands of this config's own tasks.
+	Escape string          `json:"escape,omitempty"`
+	Tasks  map[string]Task `json:"tasks"`
+
+	// Dir is the directory the config file lives in. It is empty for
+	// configs loaded from a URL.
+	Dir string `json:"-"`
+	// Profile is the name of the applied profile, if any.
+	Profile string `json:"-"`
+	// Included maps each namespace in Includes to its loaded config.
+	Included map[string]*GoferConfig `json:"-"`
+	// Raw is the config's unparsed JSON, kept so included configs can be
+	// validated alongside the root one.
+	Raw []byte `json:"-"`
 }
 
 func Load(path string) (*GoferConfig, []byte, error) {
-	data, err := os.ReadFile(path)
+	cfg, err := load(path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, cfg.Raw, nil
+}
+
+func LoadFromURL(url string) (*GoferConfig, []byte, error) {
+	cfg, err := load(url, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+		return nil, nil, err
+	}
+	return cfg, cfg.Raw, nil
+}
+
+func LoadAuto(path string) (*GoferConfig, []byte, error) {
+	if isURL(path) {
+		return LoadFromURL(path)
+	}
+	return Load(path)
+}
+
+// load reads and parses the config at location (a path or URL) and then
+// loads its includes. stack holds the locations currently being loaded and
+// is used to reject include cycles.
+func load(location string, stack []string) (*GoferConfig, error) {
+	var (
+		data []byte
+		err  error
+		dir  string
+	)
+	if isURL(location) {
+		data, err = fetch(location)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data, err = os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		if dir, err = filepath.Abs(filepath.Dir(location)); err != nil {
+			return nil, fmt.Errorf("failed to resolve config directory: %w", err)
+		}
 	}
 
 	var cfg GoferConfig
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	if cfg.EnvFile == "" {
-		cfg.EnvFile = ".env.gofer"
+	if len(cfg.EnvFile) == 0 {
+		cfg.EnvFile = EnvFiles{DefaultEnvFile}
 	}
+	cfg.Dir = dir
+	cfg.Raw = data
 
-	return &cfg, data, nil
+	if err := cfg.loadIncludes(location, append(stack[:len(stack):len(stack)], location)); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
 }
 
-func LoadFromURL(url string) (*GoferConfig, []byte, error) {
+func fetch(url string) ([]byte, error) {
 	resp, err := http.Get(url)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to fetch remote config: %w", err)
+		return nil, fmt.Errorf("failed to fetch remote config: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, nil, fmt.Errorf("failed to fetch remote config: %s", resp.Status)
+		return nil, fmt.Errorf("failed to fetch remote config: %s", resp.Status)
 	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read remote config: %w", err)
+		return nil, fmt.Errorf("failed to read remote config: %w", err)
 	}
+	return data, nil
+}
 
-	var cfg GoferConfig
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+func (c *GoferConfig) loadIncludes(location string, stack []string) error {
+	if len(c.Includes) == 0 {
+		return nil
+	}
+
+	c.Included = make(map[string]*GoferConfig, len(c.Includes))
+	for _, ns := range c.Namespaces() {
+		target, err := resolveInclude(location, c.Includes[ns])
+		if err != nil {
+			return fmt.Errorf("include %q: %w", ns, err)
+		}
+		for _, loading := range stack {
+			if sameLocation(loading, target) {
+				return fmt.Errorf("include %q: %s includes itself", ns, target)
+			}
+		}
+
+		inc, err := load(target, stack)
+		if err != nil {
+			return fmt.Errorf("include %q: %w", ns, err)
+		}
+		c.Included[ns] = inc
 	}
+	return nil
+}
 
-	if cfg.EnvFile == "" {
-		cfg.EnvFile = ".env.gofer"
+// resolveInclude resolves an include path relative to the location of the
+// config that declares it. Local includes of a remote config resolve against
+// its URL.
+func resolveInclude(from, target string) (string, error) {
+	if isURL(target) {
+		return target, nil
+	}
+	if isURL(from) {
+		base, err := url.Parse(from)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(filepath.ToSlash(target))
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+	if filepath.IsAbs(target) {
+		return target, nil
 	}
+	return filepath.Join(filepath.Dir(from), target), nil
+}
 
-	return &cfg, data, nil
+func sameLocation(a, b string) bool {
+	if isURL(a) || isURL(b) {
+		return a == b
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
 }
 
-func LoadAuto(path string) (*GoferConfig, []byte, error) {
-	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
-		return LoadFromURL(path)
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// HostWins reports whether a host value for key takes precedence over the
+// config's env files.
+func (c *GoferConfig) HostWins(key string) bool {
+	if p, ok := c.EnvPrecedenceKeys[key]; ok {
+		return p == EnvPrecedenceHost
 	}
-	return Load(path)
+	return c.EnvPrecedence == EnvPrecedenceHost
+}
+
+// HasSecrets reports whether the config or any config it includes marks a
+// param or env variable as secret.
+func (c *GoferConfig) HasSecrets() bool {
+	if len(c.EnvSecret) > 0 {
+		return true
+	}
+	for _, task := range c.Tasks {
+		if len(task.EnvSecret) > 0 || slices.ContainsFunc(task.Params, func(p Param) bool { return p.Secret }) {
+			return true
+		}
+	}
+	for _, inc := range c.Included {
+		if inc.HasSecrets() {
+			return true
+		}
+	}
+	return false
+}
+
+// Namespaces returns the names of the config's includes in sorted order.
+func (c *GoferConfig) Namespaces() []string {
+	names := make([]string, 0, len(c.Includes))
+	for ns := range c.Includes {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func (c *GoferConfig) ResolveTask(ref string) (*Task, error) {
-	if strings.Contains(ref, ".") {
-		return nil, fmt.Errorf("task %q not found (task names cannot contain dots)", ref)
+	task, _, err := c.Resolve(ref)
+	return task, err
+}
+
+// Resolve looks up a task by name. Dotted names ("backend.build") are looked
+// up in the matching included config. It also returns the config the task is
+// defined in.
+func (c *GoferConfig) Resolve(ref string) (*Task, *GoferConfig, error) {
+	owner := c
+	name := ref
+	for {
+		ns, rest, ok := strings.Cut(name, ".")
+		if !ok {
+			break
+		}
+		inc, found := owner.Included[ns]
+		if !found {
+			return nil, nil, fmt.Errorf("task %q not found (no include named %q)", ref, ns)
+		}
+		owner = inc
+		name = rest
 	}
 
-	task, ok := c.Tasks[ref]
+	task, ok := owner.Tasks[name]
 	if !ok {
-		return nil, fmt.Errorf("task %q not found", ref)
+		return nil, nil, fmt.Errorf("task %q not found", ref)
+	}
+	return &task, owner, nil
+}
+
+// Qualify resolves a task name used inside namespace ns (the namespace of the
+// task doing the referring) to a name that can be passed to Resolve.
+func Qualify(ns, ref string) string {
+	if ns == "" {
+		return ref
+	}
+	return ns + "." + ref
+}
+
+// Namespace returns the namespace part of a qualified task name.
+func Namespace(ref string) string {
+	if i := strings.LastIndex(ref, "."); i >= 0 {
+		return ref[:i]
 	}
-	return &task, nil
+	return ""
 }
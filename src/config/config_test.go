@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -54,8 +55,8 @@ func TestLoad_DefaultEnvFile(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if cfg.EnvFile != ".env.gofer" {
-		t.Errorf("env_file = %q, want %q", cfg.EnvFile, ".env.gofer")
+	if len(cfg.EnvFile) != 1 || cfg.EnvFile[0] != "?.env.gofer" {
+		t.Errorf("env_file = %q, want %q", cfg.EnvFile, "?.env.gofer")
 	}
 }
 
@@ -66,7 +67,7 @@ func TestLoad_CustomEnvFile(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if cfg.EnvFile != ".env.custom" {
+	if len(cfg.EnvFile) != 1 || cfg.EnvFile[0] != ".env.custom" {
 		t.Errorf("env_file = %q, want %q", cfg.EnvFile, ".env.custom")
 	}
 }
@@ -151,3 +152,167 @@ func TestResolveTask_DotInName(t *testing.T) {
 		t.Fatal("expected error for dot in name")
 	}
 }
+
+func TestLoad_Includes(t *testing.T) {
+	dir := t.TempDir()
+	backend := filepath.Join(dir, "backend")
+	if err := os.MkdirAll(backend, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(backend, "gofer.json"), []byte(minimalConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "gofer.json")
+	if err := os.WriteFile(root, []byte(`{"includes":{"backend":"backend/gofer.json"},"tasks":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, owner, err := cfg.Resolve("backend.hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Desc != "Say hello" {
+		t.Errorf("desc = %q, want %q", task.Desc, "Say hello")
+	}
+	if owner.Dir != backend {
+		t.Errorf("owner dir = %q, want %q", owner.Dir, backend)
+	}
+	if _, err := cfg.ResolveTask("frontend.hello"); err == nil {
+		t.Error("expected error for unknown namespace")
+	}
+	if _, err := cfg.ResolveTask("backend.missing"); err == nil {
+		t.Error("expected error for unknown task in namespace")
+	}
+}
+
+func TestLoad_IncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(dir, "b.json")
+	os.WriteFile(a, []byte(`{"includes":{"b":"b.json"},"tasks":{}}`), 0644)
+	os.WriteFile(b, []byte(`{"includes":{"a":"a.json"},"tasks":{}}`), 0644)
+
+	if _, _, err := Load(a); err == nil {
+		t.Fatal("expected error for include cycle")
+	}
+}
+
+func TestLoadAuto_URLRelativeInclude(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/configs/gofer.json":
+			w.Write([]byte(`{"includes":{"lib":"lib/gofer.json"},"tasks":{}}`))
+		case "/configs/lib/gofer.json":
+			w.Write([]byte(minimalConfig))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg, _, err := LoadAuto(srv.URL + "/configs/gofer.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.ResolveTask("lib.hello"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQualify(t *testing.T) {
+	if got := Qualify("", "build"); got != "build" {
+		t.Errorf("Qualify = %q, want %q", got, "build")
+	}
+	if got := Qualify("backend.db", "migrate"); got != "backend.db.migrate" {
+		t.Errorf("Qualify = %q, want %q", got, "backend.db.migrate")
+	}
+	if got := Namespace("backend.db.migrate"); got != "backend.db" {
+		t.Errorf("Namespace = %q, want %q", got, "backend.db")
+	}
+	if got := Namespace("build"); got != "" {
+		t.Errorf("Namespace = %q, want empty", got)
+	}
+}
+
+func TestLoad_EnvFileList(t *testing.T) {
+	path := writeConfig(t, `{"env_file": [".env.gofer", "?.env.local"], "tasks": {}}`)
+	cfg, _, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.EnvFile) != 2 || cfg.EnvFile[1] != "?.env.local" {
+		t.Errorf("env_file = %q", cfg.EnvFile)
+	}
+
+	path = writeConfig(t, `{"env_file": 1, "tasks": {}}`)
+	if _, _, err := Load(path); err == nil {
+		t.Error("expected error for a numeric env_file")
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "lib.json"), []byte(`{
+	  "profiles": {"prod": {"vars": {"where": "lib-prod"}}},
+	  "vars": {"where": "lib"},
+	  "tasks": {}
+	}`), 0644)
+	root := filepath.Join(dir, "gofer.json")
+	os.WriteFile(root, []byte(`{
+	  "includes": {"lib": "lib.json"},
+	  "env_file": ".env",
+	  "vars": {"region": "eu", "tier": "free"},
+	  "profiles": {
+	    "prod": {"env_file": ["?.env.prod"], "vars": {"tier": "paid"}, "params": {"replicas": "3"}},
+	    "dev": {}
+	  },
+	  "tasks": {"deploy": {"desc": "d", "params": [{"name": "replicas", "default": "1"}, {"name": "env"}], "steps": [{"cmd": "echo"}]}}
+	}`), 0644)
+
+	cfg, _, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ApplyProfile("staging"); err == nil || !strings.Contains(err.Error(), "available: dev, prod") {
+		t.Errorf("unknown profile error = %v", err)
+	}
+	if err := cfg.ApplyProfile("prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Profile != "prod" {
+		t.Errorf("Profile = %q, want prod", cfg.Profile)
+	}
+	if got := strings.Join(cfg.EnvFile, " "); got != ".env ?.env.prod" {
+		t.Errorf("env_file = %q", got)
+	}
+	if cfg.Vars["tier"].Value != "paid" || cfg.Vars["region"].Value != "eu" {
+		t.Errorf("vars = %v", cfg.Vars)
+	}
+	params := cfg.Tasks["deploy"].Params
+	if *params[0].Default != "3" || params[1].Default != nil {
+		t.Errorf("params = %+v", params)
+	}
+	if lib := cfg.Included["lib"]; lib.Profile != "prod" || lib.Vars["where"].Value != "lib-prod" {
+		t.Errorf("included config: profile %q, vars %v", lib.Profile, lib.Vars)
+	}
+}
+
+func TestHasSecrets(t *testing.T) {
+	cfg := &GoferConfig{Tasks: map[string]Task{"t": {Params: []Param{{Name: "p"}}}}}
+	if cfg.HasSecrets() {
+		t.Error("no secrets declared")
+	}
+	inc := &GoferConfig{Tasks: map[string]Task{"login": {Params: []Param{{Name: "password", Secret: true}}}}}
+	cfg.Included = map[string]*GoferConfig{"auth": inc}
+	if !cfg.HasSecrets() {
+		t.Error("secret param of an included config not found")
+	}
+	if !(&GoferConfig{EnvSecret: []string{"*_TOKEN"}}).HasSecrets() {
+		t.Error("env_secret not found")
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamTypes lists the accepted values of Param.Type. An empty type means
+// "string".
+var ParamTypes = []string{"string", "int", "bool", "enum", "path", "duration"}
+
+type Param struct {
+	Name    string  `json:"name"`
+	Default *string `json:"default,omitempty"`
+	Desc    string  `json:"desc,omitempty"`
+	// Type restricts the accepted values; see ParamTypes. Choices is
+	// required for "enum" and limits any other type as well. Pattern is a
+	// regular expression the whole value must match.
+	Type    string   `json:"type,omitempty"`
+	Choices []string `json:"choices,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	// Variadic makes the param (which must be the last one) collect every
+	// remaining positional argument, shell-quoted and joined with spaces.
+	Variadic bool `json:"variadic,omitempty"`
+	// Secret marks a value that must not be shown: it isn't echoed when
+	// prompted for and is redacted from output.
+	Secret bool `json:"secret,omitempty"`
+}
+
+// Validate checks value against the param's type, choices and pattern. A
+// "path" must name an existing file or directory. Errors leave out the value
+// of a secret param.
+func (p Param) Validate(value string) error {
+	if err := p.validate(value); err != nil {
+		return fmt.Errorf("param %q: %w", p.Name, err)
+	}
+	return nil
+}
+
+// ValidateDefault is Validate without the checks that depend on the
+// filesystem, for checking a config before it runs.
+func (p Param) ValidateDefault(value string) error {
+	if p.Type == "path" {
+		p.Type = "string"
+	}
+	return p.Validate(value)
+}
+
+func (p Param) validate(value string) error {
+	shown := strconv.Quote(value)
+	if p.Secret {
+		shown = "value"
+	}
+
+	switch p.Type {
+	case "", "string", "enum":
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s is not an integer", shown)
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s is not a boolean (use true or false)", shown)
+		}
+	case "duration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s is not a duration (e.g. 30s, 5m, 1h)", shown)
+		}
+	case "path":
+		if _, err := os.Stat(value); err != nil {
+			if p.Secret {
+				return errors.New("path does not exist")
+			}
+			return fmt.Errorf("path %s does not exist", shown)
+		}
+	default:
+		return fmt.Errorf("unknown type %q (must be one of %s)", p.Type, strings.Join(ParamTypes, ", "))
+	}
+
+	if len(p.Choices) > 0 && !slices.Contains(p.Choices, value) {
+		return fmt.Errorf("%s is not one of %s", shown, strings.Join(p.Choices, ", "))
+	}
+
+	if p.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + p.Pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p.Pattern, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("%s does not match pattern %s", shown, p.Pattern)
+		}
+	}
+	return nil
+}
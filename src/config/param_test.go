@@ -0,0 +1,51 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParamValidate(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		param   Param
+		value   string
+		wantErr string
+	}{
+		{name: "untyped", param: Param{Name: "p"}, value: "anything"},
+		{name: "int", param: Param{Name: "n", Type: "int"}, value: "42"},
+		{name: "bad int", param: Param{Name: "n", Type: "int"}, value: "4x", wantErr: `param "n": "4x" is not an integer`},
+		{name: "bool", param: Param{Name: "b", Type: "bool"}, value: "false"},
+		{name: "bad bool", param: Param{Name: "b", Type: "bool"}, value: "maybe", wantErr: "is not a boolean"},
+		{name: "duration", param: Param{Name: "d", Type: "duration"}, value: "1m30s"},
+		{name: "bad duration", param: Param{Name: "d", Type: "duration"}, value: "90", wantErr: "is not a duration"},
+		{name: "enum", param: Param{Name: "env", Type: "enum", Choices: []string{"dev", "prod"}}, value: "prod"},
+		{name: "bad enum", param: Param{Name: "env", Type: "enum", Choices: []string{"dev", "prod"}}, value: "prodd", wantErr: `param "env": "prodd" is not one of dev, prod`},
+		{name: "path", param: Param{Name: "src", Type: "path"}, value: dir},
+		{name: "missing path", param: Param{Name: "src", Type: "path"}, value: dir + "/nope", wantErr: "does not exist"},
+		{name: "pattern", param: Param{Name: "tag", Pattern: `v\d+`}, value: "v12"},
+		{name: "pattern is anchored", param: Param{Name: "tag", Pattern: `v\d+`}, value: "xv12", wantErr: `does not match pattern v\d+`},
+		{name: "secret", param: Param{Name: "token", Pattern: `[a-z]+`, Secret: true}, value: "Hunter2Secret", wantErr: `param "token": value does not match pattern [a-z]+`},
+		{name: "secret path", param: Param{Name: "key", Type: "path", Secret: true}, value: dir + "/Hunter2Secret", wantErr: `param "key": path does not exist`},
+		{name: "unknown type", param: Param{Name: "x", Type: "float"}, value: "1", wantErr: "unknown type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.param.Validate(tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if err != nil && tt.param.Secret && strings.Contains(err.Error(), "Hunter2Secret") {
+				t.Errorf("err = %v, shows the secret value", err)
+			}
+		})
+	}
+}
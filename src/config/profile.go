@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// EnvFiles is a list of env files, later ones overriding earlier ones. In
+// JSON it is a string or an array of strings. A path starting with "?" is
+// optional and skipped when the file doesn't exist.
+type EnvFiles []string
+
+func (e *EnvFiles) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*e = EnvFiles{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("env_file must be a string or an array of strings")
+	}
+	*e = list
+	return nil
+}
+
+// Profile is a named set of settings, selected with --profile or
+// GOFER_PROFILE, applied on top of the config's own.
+type Profile struct {
+	// EnvFile is loaded after the config's env files.
+	EnvFile EnvFiles `json:"env_file,omitempty"`
+	// Vars override the config's top-level vars, but not a task's own.
+	Vars map[string]Var `json:"vars,omitempty"`
+	// Params replace the defaults of same-named params in every task.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// ApplyProfile applies profile name to the config and to every included
+// config that defines a profile of the same name. The root config must
+// define it.
+func (c *GoferConfig) ApplyProfile(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("unknown profile %q: the config defines no profiles", name)
+		}
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	c.applyProfile(name)
+	return nil
+}
+
+func (c *GoferConfig) applyProfile(name string) {
+	c.Profile = name
+	if p, ok := c.Profiles[name]; ok {
+		c.EnvFile = append(slices.Clone(c.EnvFile), p.EnvFile...)
+		if len(p.Vars) > 0 {
+			vars := make(map[string]Var, len(c.Vars)+len(p.Vars))
+			maps.Copy(vars, c.Vars)
+			maps.Copy(vars, p.Vars)
+			c.Vars = vars
+		}
+		if len(p.Params) > 0 {
+			for tName, task := range c.Tasks {
+				task.Params = slices.Clone(task.Params)
+				for i, param := range task.Params {
+					if v, ok := p.Params[param.Name]; ok {
+						task.Params[i].Default = &v
+					}
+				}
+				c.Tasks[tName] = task
+			}
+		}
+	}
+	for _, inc := range c.Included {
+		inc.applyProfile(name)
+	}
+}
+
+// ProfileNames returns the names of the config's profiles in sorted order.
+func (c *GoferConfig) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
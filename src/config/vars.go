@@ -0,0 +1,37 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Var is an entry of a vars map. In JSON it is either a string, which is a
+// template, or {"sh": "command"}, whose trimmed stdout is the value (the
+// command is a template too).
+type Var struct {
+	Value string
+	Sh    string
+}
+
+func (v *Var) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = Var{Value: s}
+		return nil
+	}
+	var obj struct {
+		Sh *string `json:"sh"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil || obj.Sh == nil {
+		return fmt.Errorf(`var must be a string or {"sh": "command"}`)
+	}
+	*v = Var{Sh: *obj.Sh}
+	return nil
+}
+
+func (v Var) MarshalJSON() ([]byte, error) {
+	if v.Sh != "" {
+		return json.Marshal(map[string]string{"sh": v.Sh})
+	}
+	return json.Marshal(v.Value)
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVar_JSON(t *testing.T) {
+	var vars map[string]Var
+	if err := json.Unmarshal([]byte(`{"a":"lit {{.x}}","b":{"sh":"git rev-parse HEAD"}}`), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars["a"] != (Var{Value: "lit {{.x}}"}) || vars["b"] != (Var{Sh: "git rev-parse HEAD"}) {
+		t.Errorf("vars = %+v", vars)
+	}
+	out, err := json.Marshal(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), `{"a":"lit {{.x}}","b":{"sh":"git rev-parse HEAD"}}`; got != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+	if err := json.Unmarshal([]byte(`{"c":{"cmd":"x"}}`), &vars); err == nil {
+		t.Error("expected error for an object without sh")
+	}
+}
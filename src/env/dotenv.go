@@ -0,0 +1,307 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseError reports a malformed entry in an env file.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads dotenv-formatted KEY=VALUE entries from r:
+//
+//   - blank lines and lines starting with # are ignored, and so is an
+//     optional "export " before the key;
+//   - unquoted values are trimmed and end at a # preceded by whitespace;
+//   - 'single' and `backtick` quoted values are taken literally, except
+//     that a backslash escapes the quote character;
+//   - "double" quoted values understand \n, \r, \t, \", \\ and \$;
+//   - quoted values can span several lines;
+//   - $NAME and ${NAME} in unquoted and double-quoted values expand to an
+//     earlier entry in the same file, or else to lookup(NAME), or else to
+//     nothing.
+//
+// Anything else is a *ParseError naming the line.
+func Parse(r io.Reader, lookup func(string) (string, bool)) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	src := strings.ReplaceAll(string(data), "\r\n", "\n")
+	p := &parser{
+		src:    strings.TrimPrefix(src, "\uFEFF"),
+		line:   1,
+		vars:   make(map[string]string),
+		lookup: lookup,
+	}
+	return p.parse()
+}
+
+type parser struct {
+	src    string
+	pos    int
+	line   int
+	vars   map[string]string
+	lookup func(string) (string, bool)
+}
+
+func (p *parser) parse() (map[string]string, error) {
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return p.vars, nil
+		}
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+		if err := p.entry(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) entry() error {
+	if rest := p.src[p.pos:]; strings.HasPrefix(rest, "export") && len(rest) > 6 && (rest[6] == ' ' || rest[6] == '\t') {
+		p.pos += len("export")
+		p.skipSpace(false)
+	}
+
+	start := p.pos
+	for !p.eof() && isKeyChar(p.peek()) {
+		p.pos++
+	}
+	key := p.src[start:p.pos]
+	switch {
+	case key == "":
+		return p.errorf("expected a variable name, found %q", p.peek())
+	case key[0] >= '0' && key[0] <= '9':
+		return p.errorf("invalid variable name %q", key)
+	}
+
+	p.skipSpace(false)
+	if p.eof() || p.peek() == '\n' {
+		return p.errorf("%q has no value; expected %s=VALUE", key, key)
+	}
+	if p.peek() != '=' {
+		return p.errorf("unexpected %q after variable name %q", p.peek(), key)
+	}
+	p.pos++
+	p.skipSpace(false)
+
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	p.vars[key] = value
+	return nil
+}
+
+func (p *parser) value() (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+	switch q := p.peek(); q {
+	case '"', '\'', '`':
+		value, err := p.quoted(q)
+		if err != nil {
+			return "", err
+		}
+		return value, p.afterQuote()
+	default:
+		return p.unquoted()
+	}
+}
+
+// quoted reads a value enclosed in q, starting at the opening quote.
+func (p *parser) quoted(q byte) (string, error) {
+	startLine := p.line
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", &ParseError{Line: startLine, Msg: fmt.Sprintf("unterminated quoted value (missing closing %c)", q)}
+		}
+		c := p.peek()
+		switch {
+		case c == q:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src):
+			next := p.src[p.pos+1]
+			if q == '"' {
+				if esc, ok := doubleQuoteEscapes[next]; ok {
+					b.WriteByte(esc)
+					p.pos += 2
+					continue
+				}
+			} else if next == q {
+				b.WriteByte(q)
+				p.pos += 2
+				continue
+			}
+			b.WriteByte(c)
+			p.pos++
+		case c == '$' && q == '"':
+			if err := p.expand(&b); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+var doubleQuoteEscapes = map[byte]byte{
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+	'"':  '"',
+	'\\': '\\',
+	'$':  '$',
+}
+
+// afterQuote allows only whitespace and a comment after a closing quote.
+func (p *parser) afterQuote() error {
+	p.skipSpace(false)
+	switch {
+	case p.eof() || p.peek() == '\n':
+	case p.peek() == '#':
+		p.skipLine()
+	default:
+		return p.errorf("unexpected %q after closing quote", p.peek())
+	}
+	return nil
+}
+
+func (p *parser) unquoted() (string, error) {
+	var b strings.Builder
+	for !p.eof() && p.peek() != '\n' {
+		c := p.peek()
+		if c == '#' && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			p.skipLine()
+			break
+		}
+		if c == '$' {
+			if err := p.expand(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return strings.TrimRight(b.String(), " \t"), nil
+}
+
+// expand writes the value of the $NAME or ${NAME} reference at p.pos to b.
+// A $ that doesn't start a reference is kept as it is.
+func (p *parser) expand(b *strings.Builder) error {
+	p.pos++
+	if !p.eof() && p.peek() == '{' {
+		end := strings.IndexAny(p.src[p.pos:], "}\n")
+		if end < 0 || p.src[p.pos+end] != '}' {
+			return p.errorf("unterminated ${ reference")
+		}
+		name := p.src[p.pos+1 : p.pos+end]
+		if !isName(name) {
+			return p.errorf("invalid variable name %q in ${%s}", name, name)
+		}
+		b.WriteString(p.get(name))
+		p.pos += end + 1
+		return nil
+	}
+	start := p.pos
+	for !p.eof() && isNameChar(p.peek(), p.pos == start) {
+		p.pos++
+	}
+	if p.pos == start {
+		b.WriteByte('$')
+		return nil
+	}
+	b.WriteString(p.get(p.src[start:p.pos]))
+	return nil
+}
+
+// get returns the value name expands to.
+func (p *parser) get(name string) string {
+	if v, ok := p.vars[name]; ok {
+		return v
+	}
+	if p.lookup != nil {
+		if v, ok := p.lookup(name); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func (p *parser) eof() bool  { return p.pos >= len(p.src) }
+func (p *parser) peek() byte { return p.src[p.pos] }
+
+// skipSpace skips spaces and tabs, and newlines too if newlines is set.
+func (p *parser) skipSpace(newlines bool) {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t':
+		case '\n':
+			if !newlines {
+				return
+			}
+			p.line++
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+// skipLine moves to the newline ending the current line.
+func (p *parser) skipLine() {
+	if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+		p.pos += i
+	} else {
+		p.pos = len(p.src)
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &ParseError{Line: p.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isKeyChar reports whether c can appear in a key. Keys may contain dots,
+// which some tools use, but only plain names can be expanded.
+func isKeyChar(c byte) bool {
+	return isNameChar(c, false) || c == '.'
+}
+
+func isNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+func isName(s string) bool {
+	for i := range len(s) {
+		if !isNameChar(s[i], i == 0) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,99 @@
+package env
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `# comment
+export EXPORTED=yes
+	INDENTED = spaced out   
+UNQUOTED=a b # inline comment
+HASH=a#b
+EMPTY=
+SINGLE='it\'s $HOME\n'
+DOUBLE="tab\there \"quoted\" \$HOME\\"
+BACKTICK=` + "`say \"hi\" and 'bye'`" + `
+MULTI="line one
+line two"   # trailing comment
+SELF=${EXPORTED}-$UNQUOTED
+HOSTVAR=${HOST_ONLY}/bin
+MISSING=[${NOPE}]
+DOLLAR=costs $5 or $
+CRLF=value` + "\r\n" + `
+LAST="${MULTI}"`
+
+	lookup := func(name string) (string, bool) {
+		switch name {
+		case "HOST_ONLY":
+			return "/opt", true
+		case "EXPORTED":
+			return "host", true
+		}
+		return "", false
+	}
+	got, err := Parse(strings.NewReader(src), lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"EXPORTED": "yes",
+		"INDENTED": "spaced out",
+		"UNQUOTED": "a b",
+		"HASH":     "a#b",
+		"EMPTY":    "",
+		"SINGLE":   `it's $HOME\n`,
+		"DOUBLE":   "tab\there \"quoted\" $HOME\\",
+		"BACKTICK": `say "hi" and 'bye'`,
+		"MULTI":    "line one\nline two",
+		"SELF":     "yes-a b",
+		"HOSTVAR":  "/opt/bin",
+		"MISSING":  "[]",
+		"DOLLAR":   "costs $5 or $",
+		"CRLF":     "value",
+		"LAST":     "line one\nline two",
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s = %q, want %q", k, got[k], w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d vars, want %d: %q", len(got), len(want), got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		msg  string
+	}{
+		{"no equals", "A=1\nB\n", 2, `"B" has no value`},
+		{"bad name", "A=1\n\n9LIVES=x\n", 3, `invalid variable name "9LIVES"`},
+		{"junk in key", "MY KEY=x\n", 1, `unexpected 'K' after variable name "MY"`},
+		{"unterminated", "A=1\nB=\"open\nstill open\n", 2, "unterminated quoted value"},
+		{"after quote", "A='x' y\n", 1, "after closing quote"},
+		{"unterminated reference", "A=${B\n", 1, "unterminated ${"},
+		{"bad reference", "A=${B-C}\n", 1, "invalid variable name"},
+		{"line after multiline", "A=\"1\n2\"\n=x\n", 3, "expected a variable name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.src), nil)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("err = %v, want a *ParseError", err)
+			}
+			if perr.Line != tt.line {
+				t.Errorf("line = %d, want %d (%v)", perr.Line, tt.line, err)
+			}
+			if !strings.Contains(perr.Msg, tt.msg) {
+				t.Errorf("msg = %q, want it to contain %q", perr.Msg, tt.msg)
+			}
+		})
+	}
+}
@@ -1,56 +1,129 @@
 package env
 
 import (
-	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
-func LoadEnvFile(path string) (map[string]string, error) {
-	vars := make(map[string]string)
-
-	f, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return vars, nil
+// LoadEnvFiles loads each of paths in order into one map, later files
+// overriding earlier ones; references in a file can use the values of the
+// files before it. Relative paths are resolved against dir. A path starting
+// with "?" is optional and skipped when it doesn't exist; any other missing
+// file is an error. sources maps each variable to the entry of paths (less
+// the "?") its value came from.
+func LoadEnvFiles(dir string, paths []string) (vars, sources map[string]string, err error) {
+	vars = make(map[string]string)
+	sources = make(map[string]string)
+	lookup := func(name string) (string, bool) {
+		if v, ok := vars[name]; ok {
+			return v, true
 		}
-		return nil, err
+		return os.LookupEnv(name)
 	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	for _, p := range paths {
+		path, optional := strings.CutPrefix(p, "?")
+		if dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			if optional && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, nil, err
 		}
-		key, value, ok := strings.Cut(line, "=")
-		if !ok {
-			continue
+		fileVars, err := Parse(f, lookup)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+			sources[k] = strings.TrimPrefix(p, "?")
 		}
-		vars[strings.TrimSpace(key)] = strings.TrimSpace(value)
 	}
-
-	return vars, scanner.Err()
+	return vars, sources, nil
 }
 
-func BuildEnv(envFileVars map[string]string) []string {
-	envMap := make(map[string]string)
-
-	for _, entry := range os.Environ() {
-		key, value, ok := strings.Cut(entry, "=")
-		if ok {
-			envMap[key] = value
+// Overlay returns a copy of env, a list of KEY=VALUE entries, with vars set
+// on top. Keys already in env keep their position and new ones are appended
+// in sorted order, so the same inputs always give the same list.
+func Overlay(env []string, vars map[string]string) []string {
+	result := make([]string, 0, len(env)+len(vars))
+	seen := make(map[string]bool, len(vars))
+	for _, entry := range env {
+		key, _, _ := strings.Cut(entry, "=")
+		if v, ok := vars[key]; ok {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			entry = key + "=" + v
 		}
+		result = append(result, entry)
 	}
-
-	for k, v := range envFileVars {
-		envMap[k] = v
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		if !seen[k] {
+			result = append(result, k+"="+vars[k])
+		}
 	}
+	return result
+}
 
-	result := make([]string, 0, len(envMap))
-	for k, v := range envMap {
-		result = append(result, k+"="+v)
+// Allow returns the entries of env, a list of KEY=VALUE entries, whose key
+// matches one of patterns (path.Match syntax, e.g. "LC_*").
+func Allow(env []string, patterns []string) []string {
+	var result []string
+	for _, entry := range env {
+		key, _, _ := strings.Cut(entry, "=")
+		if Matches(key, patterns) {
+			result = append(result, entry)
+		}
 	}
 	return result
 }
+
+// Matches reports whether the variable name key matches one of patterns
+// (path.Match syntax).
+func Matches(key string, patterns []string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		ok, _ := path.Match(p, key)
+		return ok
+	})
+}
+
+// secretWords are the words of variable names that hold secrets, as in
+// GITHUB_TOKEN, DB_PASSWORD or AWS_SECRET_ACCESS_KEY.
+var secretWords = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "PASS", "KEY", "APIKEY", "CREDENTIALS", "CREDENTIAL", "PRIVATE", "COOKIE"}
+
+// secretPrefixes are the prefixes of well-known kinds of credentials.
+var secretPrefixes = []string{"ghp_", "gho_", "ghs_", "ghu_", "github_pat_", "glpat-", "sk-", "xoxb-", "xoxp-", "AKIA", "-----BEGIN "}
+
+// LooksSecret reports whether the variable name, with the given value,
+// probably holds a secret: a name with a word like TOKEN or PASSWORD in it,
+// a value with the prefix of a well-known kind of API token or private key,
+// or a JSON web token. It is a heuristic for masking values on display.
+func LooksSecret(name, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, word := range strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		if slices.Contains(secretWords, word) {
+			return true
+		}
+	}
+	for _, prefix := range secretPrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	// A JWT is three base64url parts, the first an encoded JSON object.
+	return strings.HasPrefix(value, "eyJ") && strings.Count(value, ".") == 2 && !strings.ContainsAny(value, " \t\n")
+}
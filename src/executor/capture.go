@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azmekk/gofer/config"
+)
+
+// captures holds the values stored by capture and capture_exit. Steps of
+// a concurrent block share their task's captures, hence the mutex.
+type captures struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func (c *captures) set(name, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]string)
+	}
+	c.m[name] = value
+}
+
+// snapshot returns a copy of the captured values; it is safe on a nil c.
+func (c *captures) snapshot() map[string]string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.m)
+}
+
+// captureStdout returns the writer a cmd step's stdout goes to, and a buffer
+// holding a copy of it when the step captures its output.
+func captureStdout(step config.Step, stdout io.Writer) (io.Writer, *bytes.Buffer) {
+	if step.Silent {
+		stdout = io.Discard
+	}
+	if step.Capture == "" {
+		return stdout, nil
+	}
+	buf := &bytes.Buffer{}
+	if step.Silent {
+		return buf, buf
+	}
+	return io.MultiWriter(stdout, buf), buf
+}
+
+// storeCaptures records a finished cmd step's trimmed stdout and exit code
+// in f.captures, as configured by the step, and returns the error the step
+// should report. With capture_exit set a non-zero exit status is a value,
+// not a failure.
+func storeCaptures(ctx context.Context, step config.Step, f *frame, out *bytes.Buffer, err error) error {
+	if out != nil {
+		f.captures.set(step.Capture, strings.TrimSpace(out.String()))
+	}
+	if step.CaptureExit == "" {
+		return err
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		f.captures.set(step.CaptureExit, "0")
+	case errors.As(err, &exitErr) && ctx.Err() == nil && exitErr.ExitCode() >= 0:
+		f.captures.set(step.CaptureExit, strconv.Itoa(exitErr.ExitCode()))
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Azmekk/gofer/config"
+)
+
+// conditionMet evaluates a step's if and if_cmd conditions. Both must hold
+// for the step to run.
+//
+// The if template is false when it renders to an empty string or to
+// something strconv.ParseBool reads as false ("false", "0", ...); anything
+// else is true. if_cmd is true when the command exits with status 0.
+func (e *Executor) conditionMet(ctx context.Context, step config.Step, f *frame) (bool, error) {
+	if step.If != "" {
+		resolved, err := f.resolve(step.If)
+		if err != nil {
+			return false, fmt.Errorf("if: %w", err)
+		}
+		if !truthy(resolved) {
+			return false, nil
+		}
+	}
+
+	if step.IfCmd != "" {
+		resolved, err := f.resolveShell(step.IfCmd)
+		if err != nil {
+			return false, fmt.Errorf("if_cmd: %w", err)
+		}
+		cmd := ShellCommandContext(ctx, resolved, e.GracePeriod)
+		cmd.Env = f.env
+		cmd.Dir = f.dir
+		cmd.Stderr = e.Stderr
+		err = cmd.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("if_cmd: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+func truthy(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b
+	}
+	return true
+}
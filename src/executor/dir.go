@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// resolveDir resolves a task or step dir template. A relative result is
+// taken relative to the directory of the task's config file. The directory
+// must exist.
+func (f *frame) resolveDir(src string) (string, error) {
+	dir, err := f.resolve(src)
+	if err != nil {
+		return "", fmt.Errorf("dir: %w", err)
+	}
+	if !filepath.IsAbs(dir) && f.base != "" {
+		dir = filepath.Join(f.base, dir)
+	}
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("dir %q does not exist", dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("dir: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("dir %q is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// withDir returns a copy of f that runs commands in dir. The copy shares
+// f's captures, so values captured under a step's dir are still seen by the
+// rest of the task.
+func (f *frame) withDir(dir string) *frame {
+	sub := *f
+	sub.dir = dir
+	return &sub
+}
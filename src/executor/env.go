@@ -0,0 +1,261 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Azmekk/gofer/config"
+	goferenv "github.com/Azmekk/gofer/env"
+	"github.com/Azmekk/gofer/output"
+)
+
+// baseEnv builds the environment f starts with, in this order:
+//
+//   - the host environment, or with env_clear only its allowed variables;
+//   - the owner config's env files (the active profile's included);
+//   - the env settings passed on by caller, the frame of the ref step
+//     running this task;
+//   - the task's env files.
+//
+// Env file values don't override host values the owner config gives
+// precedence to.
+func (e *Executor) baseEnv(f *frame, owner *config.GoferConfig, caller *frame) error {
+	host := e.Env
+	if host == nil {
+		host = os.Environ()
+	}
+	if f.task.EnvClear {
+		host = goferenv.Allow(host, f.task.EnvAllow)
+	}
+	inHost := make(map[string]bool, len(host))
+	f.envSrc = make(map[string]string, len(host))
+	for _, entry := range host {
+		key, _, _ := strings.Cut(entry, "=")
+		inHost[key] = true
+		f.envSrc[key] = "host"
+	}
+	// fromFiles drops the values host ones win over and labels the rest
+	// with their file.
+	fromFiles := func(vars, sources map[string]string, profile bool) (map[string]string, func(string) string) {
+		maps.DeleteFunc(vars, func(k, _ string) bool { return inHost[k] && owner.HostWins(k) })
+		return vars, func(k string) string { return envFileSource(owner, f.ref, sources[k], profile) }
+	}
+
+	// The root config's env files are relative to the working directory.
+	dir := owner.Dir
+	if owner == e.Config {
+		dir = ""
+	}
+	vars, sources, err := goferenv.LoadEnvFiles(dir, owner.EnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to load env file: %w", err)
+	}
+	vars, source := fromFiles(vars, sources, true)
+	f.env = goferenv.Overlay(host, vars)
+	for k := range vars {
+		f.envSrc[k] = source(k)
+	}
+
+	if caller != nil {
+		f.setEnv(caller.envVars, func(k string) string { return caller.envSrc[k] })
+	}
+	if len(f.task.EnvFile) > 0 {
+		vars, sources, err := goferenv.LoadEnvFiles(f.base, f.task.EnvFile)
+		if err != nil {
+			return fmt.Errorf("failed to load env file: %w", err)
+		}
+		f.setEnv(fromFiles(vars, sources, false))
+	}
+	f.addSecretEnv(f.env)
+	return nil
+}
+
+// checkRequired reports the variables in required that env leaves unset or
+// empty.
+func checkRequired(env []string, required []string) error {
+	if len(required) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		values[key] = value
+	}
+	var missing []string
+	for _, name := range required {
+		if values[name] == "" && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required environment variable(s) %s; set them in the environment or an env file", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// resolveTaskEnv sets the task's env values, which are templates, on top of
+// the frame's environment. They can use the task's vars, so this runs after
+// resolveVars.
+func (f *frame) resolveTaskEnv() error {
+	vars, err := f.resolveEnv(f.task.Env)
+	if err != nil {
+		return err
+	}
+	f.setEnv(vars, func(string) string { return "task " + f.ref })
+	return nil
+}
+
+// withEnv returns a copy of f with the env values of step set. The copy
+// shares f's captures.
+func (f *frame) withEnv(step config.Step, index int) (*frame, error) {
+	vars, err := f.resolveEnv(step.Env)
+	if err != nil {
+		return nil, err
+	}
+	sub := *f
+	sub.envVars = maps.Clone(f.envVars)
+	sub.envSrc = maps.Clone(f.envSrc)
+	sub.setEnv(vars, func(string) string { return stepEnvSource(f, step, index) })
+	return &sub, nil
+}
+
+// resolveEnv resolves the templates of an env map against f.
+func (f *frame) resolveEnv(env map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(env))
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		v, err := f.resolve(env[k])
+		if err != nil {
+			return nil, fmt.Errorf("env %q: %w", k, err)
+		}
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// setEnv sets vars in f's environment and records them in f.envVars so
+// tasks run by ref steps inherit them. source names where each value came
+// from, for gofer env.
+func (f *frame) setEnv(vars map[string]string, source func(key string) string) {
+	if len(vars) == 0 {
+		return
+	}
+	if f.envVars == nil {
+		f.envVars = make(map[string]string, len(vars))
+	}
+	if f.envSrc == nil {
+		f.envSrc = make(map[string]string, len(vars))
+	}
+	for k, v := range vars {
+		f.envVars[k] = v
+		f.envSrc[k] = source(k)
+		if f.isSecretEnv(k) {
+			f.secrets.Add(v)
+		}
+	}
+	f.env = goferenv.Overlay(f.env, vars)
+}
+
+// envFileSource describes the env file path, as listed in owner or the task
+// ref, for gofer env. Files of included configs are prefixed with their
+// namespace and, when profile is set, those the active profile adds are
+// marked as such.
+func envFileSource(owner *config.GoferConfig, ref, path string, profile bool) string {
+	src := path
+	if ns := config.Namespace(ref); ns != "" {
+		src = ns + ": " + path
+	}
+	if p, ok := owner.Profiles[owner.Profile]; profile && ok && slices.ContainsFunc(p.EnvFile, func(file string) bool {
+		return strings.TrimPrefix(file, "?") == path
+	}) {
+		src += " (profile " + owner.Profile + ")"
+	}
+	return src
+}
+
+// stepEnvSource describes the env of step of f's task for gofer env.
+func stepEnvSource(f *frame, step config.Step, index int) string {
+	return fmt.Sprintf("step %q of task %s", output.StepLabel(step, index), f.ref)
+}
+
+// EnvVar is a variable of a resolved environment.
+type EnvVar struct {
+	Name  string
+	Value string
+	// Source is where the value came from: "host", an env file, or the
+	// task or step env that set it.
+	Source string
+	// Secret is set for variables env_secret marks as secret.
+	Secret bool
+}
+
+// StepEnv holds the variables a step sets with its own env.
+type StepEnv struct {
+	Step string // the step's label
+	Env  []EnvVar
+}
+
+// TaskEnv returns the environment the commands of task ref run with, sorted
+// by name, and the env its steps (and their concurrent steps) set on top of
+// it, in order. It resolves the task's params and vars, running its sh vars,
+// but no deps or steps. Step env values that use captures can't be known
+// before the task runs and are returned as written, marked unresolved. An
+// empty ref gives the environment of the root config alone.
+func (e *Executor) TaskEnv(ctx context.Context, ref string) ([]EnvVar, []StepEnv, error) {
+	if ref == "" {
+		f := &frame{task: &config.Task{}, base: e.Config.Dir, secretEnv: e.Config.EnvSecret}
+		if err := e.baseEnv(f, e.Config, nil); err != nil {
+			return nil, nil, err
+		}
+		return f.envList(), nil, nil
+	}
+
+	f, err := e.newFrame(ctx, ref, e.Params, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.resolveVars(ctx, f); err != nil {
+		return nil, nil, err
+	}
+	if err := f.resolveTaskEnv(); err != nil {
+		return nil, nil, fmt.Errorf("task %q: %w", f.ref, err)
+	}
+	var steps []StepEnv
+	f.stepEnvs(f.task.Steps, &steps)
+	f.stepEnvs(f.task.Finally, &steps)
+	return f.envList(), steps, nil
+}
+
+// envList returns f's environment sorted by name.
+func (f *frame) envList() []EnvVar {
+	vars := make([]EnvVar, 0, len(f.env))
+	for _, entry := range f.env {
+		key, value, _ := strings.Cut(entry, "=")
+		vars = append(vars, EnvVar{Name: key, Value: value, Source: f.envSrc[key], Secret: f.isSecretEnv(key)})
+	}
+	slices.SortStableFunc(vars, func(a, b EnvVar) int { return strings.Compare(a.Name, b.Name) })
+	return vars
+}
+
+// stepEnvs appends the env of each of steps that sets one to out.
+func (f *frame) stepEnvs(steps []config.Step, out *[]StepEnv) {
+	for i, step := range steps {
+		if len(step.Env) > 0 {
+			src := stepEnvSource(f, step, i)
+			se := StepEnv{Step: output.StepLabel(step, i)}
+			for _, k := range slices.Sorted(maps.Keys(step.Env)) {
+				v, err := f.resolve(step.Env[k])
+				source := src
+				if err != nil {
+					v, source = step.Env[k], src+" (unresolved)"
+				}
+				se.Env = append(se.Env, EnvVar{Name: k, Value: v, Source: source, Secret: f.isSecretEnv(k)})
+			}
+			*out = append(*out, se)
+		}
+		f.stepEnvs(step.Concurrent, out)
+	}
+}
@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"text/template"
+	"text/template/parse"
+)
+
+// escapeFunc is the hidden function escapeActions appends to actions.
+const escapeFunc = "_gofer_escape"
+
+// rawFuncs are the template functions whose result escapeActions leaves
+// alone: raw opts out of quoting and shellquote has already quoted.
+var rawFuncs = []string{"raw", "shellquote"}
+
+// escapeActions rewrites every {{ pipeline }} that produces output in tmpl,
+// including those nested in if, range and with blocks, into
+// {{ pipeline | _gofer_escape }} so each interpolated value is quoted before
+// it reaches the shell. Left alone are variable declarations (they print
+// nothing), pipelines ending in a rawFuncs function, and bare references to
+// the fields in quoted, whose values are already quoted lists. Values that
+// aren't strings are formatted with fmt.Sprint before being quoted.
+func escapeActions(tmpl *template.Template, quote func(string) string, quoted []string) {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			escapeNode(t.Tree, t.Tree.Root, quoted)
+		}
+	}
+	tmpl.Funcs(template.FuncMap{escapeFunc: func(v any) string { return quote(fmt.Sprint(v)) }})
+}
+
+func escapeNode(tree *parse.Tree, node parse.Node, quoted []string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			escapeNode(tree, c, quoted)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || isRaw(n.Pipe, quoted) {
+			return
+		}
+		ident := parse.NewIdentifier(escapeFunc).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{ident},
+		})
+	case *parse.IfNode:
+		escapeNode(tree, n.List, quoted)
+		escapeNode(tree, n.ElseList, quoted)
+	case *parse.RangeNode:
+		escapeNode(tree, n.List, quoted)
+		escapeNode(tree, n.ElseList, quoted)
+	case *parse.WithNode:
+		escapeNode(tree, n.List, quoted)
+		escapeNode(tree, n.ElseList, quoted)
+	}
+}
+
+// isRaw reports whether pipe's output must not be quoted again.
+func isRaw(pipe *parse.PipeNode, quoted []string) bool {
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && slices.Contains(rawFuncs, ident.Ident) {
+		return true
+	}
+	if len(pipe.Cmds) == 1 && len(last.Args) == 1 {
+		if field, ok := last.Args[0].(*parse.FieldNode); ok && len(field.Ident) == 1 {
+			return slices.Contains(quoted, field.Ident[0])
+		}
+	}
+	return false
+}
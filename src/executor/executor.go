@@ -1,163 +1,545 @@
 package executor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"runtime"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/Azmekk/gofer/config"
+	"github.com/Azmekk/gofer/fingerprint"
 	"github.com/Azmekk/gofer/output"
 )
 
 type Executor struct {
-	Config  *config.GoferConfig
-	Env     []string
-	Params  map[string]string
-	Stdout  io.Writer
-	Stderr  io.Writer
-	running map[string]bool
+	Config *config.GoferConfig
+	// Env is the host environment the configs' env files are layered on.
+	// Nil means the process environment.
+	Env    []string
+	Params map[string]string
+	Stdout io.Writer
+	Stderr io.Writer
+	// Force runs tasks with sources/generates even when they are up to date.
+	Force bool
+	// StateDir is where up-to-date fingerprints are stored.
+	StateDir string
+	// GracePeriod is how long a cancelled command gets to exit after being
+	// signalled before it is killed.
+	GracePeriod time.Duration
+	// FailFast cancels the remaining branches of every concurrent block (and
+	// parallel deps) as soon as one of them fails.
+	FailFast bool
+	// Jobs bounds how many commands run at once across the whole run,
+	// including nested concurrent blocks and parallel deps. 0 means no limit.
+	Jobs int
+	// Args are the arguments given after "--" on the command line, available
+	// to templates as {{.ARGS}}.
+	Args []string
+	// Prompt, when set, is asked for the value of a required param that
+	// wasn't given instead of failing the task. It is called at most once
+	// per task and param, and never concurrently.
+	Prompt func(ctx context.Context, ref string, p config.Param) (string, error)
+	sched  *scheduler
+}
+
+// frame is the context a single task invocation runs in.
+type frame struct {
+	ref       string // fully qualified task name
+	task      *config.Task
+	params    map[string]string
+	env       []string
+	envVars   map[string]string // task and step env set on top of env; ref steps pass them on
+	envSrc    map[string]string // where each variable of env came from, see TaskEnv
+	secrets   *output.Secrets   // the run's secrets, see redactOutput
+	secretEnv []string          // env_secret patterns of the owning config and the task
+	required  []string          // env_required of the owning config and the task
+	dir       string            // working directory; empty means the current directory
+	base      string            // directory of the owning config; dir fields are relative to it
+	args      []string          // passthrough arguments, see Executor.Args
+	escape    bool              // quote values interpolated into commands, see resolveShell
+
+	varDefs  map[string]config.Var // the owning config's vars and the task's
+	vars     map[string]string     // resolved vars, see resolveVars
+	captures *captures             // values stored by capture steps
+}
+
+// ns returns the namespace refs inside this task are resolved against.
+func (f *frame) ns() string {
+	return config.Namespace(f.ref)
 }
 
 func New(cfg *config.GoferConfig, env []string, params map[string]string) *Executor {
 	return &Executor{
-		Config:  cfg,
-		Env:     env,
-		Params:  params,
-		Stdout:  os.Stdout,
-		Stderr:  os.Stderr,
-		running: make(map[string]bool),
+		Config:      cfg,
+		Env:         env,
+		Params:      params,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		StateDir:    fingerprint.DefaultDir,
+		GracePeriod: DefaultGracePeriod,
+		sched:       newScheduler(),
 	}
 }
 
-func (e *Executor) RunTask(ref string) error {
-	if e.running[ref] {
-		return fmt.Errorf("cycle detected: task %q is already running", ref)
+// child returns a copy of e that writes to the given writers and shares e's
+// run-wide state.
+func (e *Executor) child(stdout, stderr io.Writer) *Executor {
+	c := *e
+	c.Stdout = stdout
+	c.Stderr = stderr
+	return &c
+}
+
+// RunTask checks the task graph reachable from ref for cycles and then runs
+// the task, its dependencies first.
+func (e *Executor) RunTask(ctx context.Context, ref string) error {
+	if _, err := e.Config.ResolveTask(ref); err != nil {
+		return err
 	}
-	e.running[ref] = true
-	defer func() { delete(e.running, ref) }()
+	if err := checkCycles(e.Config, ref); err != nil {
+		return err
+	}
+	e.sched.limit(e.Jobs)
+	if e.Config.HasSecrets() {
+		defer e.redactOutput()()
+	}
+	return redactError(e.sched.secrets, e.runTask(ctx, ref, e.Params))
+}
 
-	task, err := e.Config.ResolveTask(ref)
+func (e *Executor) runTask(ctx context.Context, ref string, params map[string]string) error {
+	f, err := e.newFrame(ctx, ref, params, nil)
 	if err != nil {
 		return err
 	}
+	return e.runFrame(ctx, f)
+}
 
-	resolved := make(map[string]string)
-	for k, v := range e.Params {
-		resolved[k] = v
+// newFrame resolves a task and prepares the params, environment and working
+// directory it runs with. Tasks from included configs run in their config's
+// directory. caller is the frame of the ref step running the task, if any;
+// the new frame starts with its captures and env settings.
+func (e *Executor) newFrame(ctx context.Context, ref string, params map[string]string, caller *frame) (*frame, error) {
+	task, owner, err := e.Config.Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := e.resolveParams(ctx, ref, task, params)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &frame{ref: ref, task: task, params: resolved, args: e.Args, captures: &captures{}}
+	f.secrets = e.sched.secrets
+	f.secretEnv = slices.Concat(owner.EnvSecret, task.EnvSecret)
+	f.required = slices.Concat(owner.EnvRequired, task.EnvRequired)
+	f.addSecretParams()
+	f.escape = owner.Escape == config.EscapeAuto
+	f.base = owner.Dir
+	if len(owner.Vars) > 0 || len(task.Vars) > 0 {
+		f.varDefs = make(map[string]config.Var, len(owner.Vars)+len(task.Vars))
+		maps.Copy(f.varDefs, owner.Vars)
+		maps.Copy(f.varDefs, task.Vars)
+	}
+	if owner != e.Config {
+		f.dir = owner.Dir
+	}
+	if caller != nil {
+		f.captures.m = caller.captures.snapshot()
+	}
+	if err := e.baseEnv(f, owner, caller); err != nil {
+		return nil, fmt.Errorf("task %q: %w", ref, err)
+	}
+	return f, nil
+}
+
+// runFrame runs a prepared task: its deps first, then its steps unless the
+// task's sources and generates are unchanged since the last run. The task's
+// dir and env are resolved after its vars, so they can use them, and
+// env_required is checked once its env is complete.
+func (e *Executor) runFrame(ctx context.Context, f *frame) error {
+	if err := e.runDeps(ctx, f); err != nil {
+		return err
+	}
+	if err := e.resolveVars(ctx, f); err != nil {
+		return err
+	}
+	if f.task.Dir != "" {
+		dir, err := f.resolveDir(f.task.Dir)
+		if err != nil {
+			return fmt.Errorf("task %q: %w", f.ref, err)
+		}
+		f.dir = dir
+	}
+	if err := f.resolveTaskEnv(); err != nil {
+		return fmt.Errorf("task %q: %w", f.ref, err)
+	}
+	if err := checkRequired(f.env, f.required); err != nil {
+		return fmt.Errorf("task %q: %w", f.ref, err)
+	}
+	if len(f.task.Sources) > 0 || len(f.task.Generates) > 0 {
+		return e.runIfOutdated(ctx, f)
+	}
+	return e.runSteps(ctx, f)
+}
+
+// runSteps runs a task's steps followed by its finally steps. The finally
+// steps run even when the steps failed or ctx was cancelled, using a context
+// that ignores the cancellation unless gofer is interrupted a second time;
+// each of them runs regardless of the others' results, and their errors are
+// joined after the original one.
+func (e *Executor) runSteps(ctx context.Context, f *frame) error {
+	err := e.executeSteps(ctx, f.task.Steps, f)
+	if len(f.task.Finally) == 0 {
+		return err
+	}
+	errs := []error{err}
+	cleanupCtx, release := cleanupContext(ctx)
+	defer release()
+	for i, step := range f.task.Finally {
+		errs = append(errs, e.executeStep(cleanupCtx, step, f, i))
+	}
+	return errors.Join(errs...)
+}
+
+// PositionalParams assigns positional command-line args to the task's params
+// in declaration order. A variadic last param takes all remaining args
+// (each one validated, then quoted with QuoteArgs); any other surplus
+// argument is an error.
+func PositionalParams(ref string, task *config.Task, args []string) (map[string]string, error) {
+	params := make(map[string]string)
+	for i, p := range task.Params {
+		if i >= len(args) {
+			break
+		}
+		if p.Variadic {
+			for _, a := range args[i:] {
+				if err := p.Validate(a); err != nil {
+					return nil, fmt.Errorf("task %q: %w", ref, err)
+				}
+			}
+			params[p.Name] = QuoteArgs(args[i:])
+			return params, nil
+		}
+		params[p.Name] = args[i]
+	}
+	if len(args) > len(task.Params) {
+		extra := args[len(task.Params):]
+		return nil, fmt.Errorf("task %q takes %d positional argument(s), got %d (unexpected %s); use -- to pass arguments through to {{.ARGS}}",
+			ref, len(task.Params), len(args), QuoteArgs(extra))
 	}
+	return params, nil
+}
+
+// VariadicArgs splits value, given for the variadic param p, into its
+// arguments with SplitArgs and validates each of them. A value from
+// PositionalParams comes back as the original arguments; any other value,
+// such as a -p flag or a default, is split like a shell would.
+func VariadicArgs(p config.Param, value string) ([]string, error) {
+	args, err := SplitArgs(value)
+	if err != nil {
+		if p.Secret {
+			return nil, fmt.Errorf("parameter %q: unterminated quote", p.Name)
+		}
+		return nil, fmt.Errorf("parameter %q: %w", p.Name, err)
+	}
+	for _, a := range args {
+		if err := p.Validate(a); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
 
+// resolveParams picks the values of the task's declared params from the
+// given ones (anything undeclared is dropped), fills in defaults, asks
+// e.Prompt for required params that are still missing (or fails if it is
+// nil), and rejects any value its param definition doesn't accept. Variadic
+// params are checked argument by argument, wherever their value came from,
+// and always end up quoted with QuoteArgs. Secret values are added to the
+// run's secrets before they are checked.
+func (e *Executor) resolveParams(ctx context.Context, ref string, task *config.Task, params map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string)
 	for _, p := range task.Params {
-		if _, ok := resolved[p.Name]; !ok {
-			if p.Default != nil {
+		if v, ok := params[p.Name]; ok {
+			resolved[p.Name] = v
+		} else {
+			switch {
+			case p.Default != nil:
 				resolved[p.Name] = *p.Default
-			} else {
-				return fmt.Errorf("task %q: missing required parameter %q", ref, p.Name)
+			case e.Prompt != nil:
+				value, err := e.sched.prompt(ref+"\x00"+p.Name, func() (string, error) {
+					return e.Prompt(ctx, ref, p)
+				})
+				if err != nil {
+					return nil, fmt.Errorf("task %q: parameter %q: %w", ref, p.Name, err)
+				}
+				resolved[p.Name] = value
+			default:
+				return nil, fmt.Errorf("task %q: missing required parameter %q", ref, p.Name)
+			}
+		}
+		if p.Secret {
+			e.sched.secrets.Add(resolved[p.Name])
+		}
+		if p.Variadic {
+			// However the value was given, it is stored as a quoted list.
+			args, err := VariadicArgs(p, resolved[p.Name])
+			if err != nil {
+				return nil, fmt.Errorf("task %q: %w", ref, err)
 			}
+			resolved[p.Name] = QuoteArgs(args)
+			continue
+		}
+		if err := p.Validate(resolved[p.Name]); err != nil {
+			return nil, fmt.Errorf("task %q: %w", ref, err)
 		}
 	}
+	return resolved, nil
+}
+
+// runDeps runs a task's dependencies, in parallel when there is more than one.
+func (e *Executor) runDeps(ctx context.Context, f *frame) error {
+	deps := make([]string, len(f.task.Deps))
+	for i, d := range f.task.Deps {
+		deps[i] = config.Qualify(f.ns(), d)
+	}
+
+	switch len(deps) {
+	case 0:
+		return nil
+	case 1:
+		return e.runDep(ctx, deps[0], f.params)
+	}
+
+	label := fmt.Sprintf("deps of %s (%d tasks)", f.ref, len(deps))
+	err := e.fanOut(ctx, label, deps, e.FailFast, 0, func(ctx context.Context, child *Executor, i int) error {
+		return child.runDep(ctx, deps[i], f.params)
+	})
+	return e.finishStep(ctx, label, false, err)
+}
+
+// runDep runs a dependency through the scheduler so it executes at most once
+// per invocation for a given set of params.
+func (e *Executor) runDep(ctx context.Context, ref string, params map[string]string) error {
+	f, err := e.newFrame(ctx, ref, params, nil)
+	if err != nil {
+		return err
+	}
 
-	return e.executeSteps(task.Steps, resolved)
+	return e.sched.once(runKey(f), func() error {
+		output.PrintStepStart(e.Stderr, ref)
+		return e.finishStep(ctx, ref, false, e.runFrame(ctx, f))
+	})
 }
 
-func (e *Executor) executeSteps(steps []config.Step, params map[string]string) error {
+// executeSteps runs steps in order, stopping at the first failure unless the
+// task has continue_on_error, in which case all failures are joined.
+// Interruptions and cancellations always stop the sequence.
+func (e *Executor) executeSteps(ctx context.Context, steps []config.Step, f *frame) error {
+	var errs []error
 	for i, step := range steps {
-		if err := e.executeStep(step, params, i); err != nil {
-			return err
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, context.Cause(ctx))...)
+		}
+		if err := e.executeStep(ctx, step, f, i); err != nil {
+			if !f.task.ContinueOnError || stopped(err) {
+				return errors.Join(append(errs, err)...)
+			}
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-func (e *Executor) executeStep(step config.Step, params map[string]string, index int) error {
+func (e *Executor) executeStep(ctx context.Context, step config.Step, f *frame, index int) error {
 	if !shouldRun(step.OS) {
 		return nil
 	}
+	if step.Dir != "" {
+		dir, err := f.resolveDir(step.Dir)
+		if err != nil {
+			return e.stepFailed(ctx, output.StepLabel(step, index), err)
+		}
+		f = f.withDir(dir)
+	}
+	if len(step.Env) > 0 {
+		var err error
+		if f, err = f.withEnv(step, index); err != nil {
+			return e.stepFailed(ctx, output.StepLabel(step, index), err)
+		}
+	}
+	if step.If != "" || step.IfCmd != "" {
+		label := output.StepLabel(step, index)
+		ok, err := e.conditionMet(ctx, step, f)
+		if err != nil {
+			return e.stepFailed(ctx, label, err)
+		}
+		if !ok {
+			output.PrintStepSkip(e.Stderr, label, "condition not met")
+			return nil
+		}
+	}
+	if step.Timeout != "" || step.Retry != nil {
+		return e.runWithRetry(ctx, step, f, index)
+	}
+	return e.runStep(ctx, step, f, index)
+}
 
+// runStep runs a single attempt of a step.
+func (e *Executor) runStep(ctx context.Context, step config.Step, f *frame, index int) error {
 	switch {
 	case step.Cmd != "":
 		label := output.StepLabel(step, index)
+		if err := e.sched.acquire(ctx); err != nil {
+			return e.stepFailed(ctx, label, err)
+		}
+		defer e.sched.release()
 		output.PrintStepStart(e.Stderr, label)
-		resolved, err := ResolveTemplate(step.Cmd, params)
+		resolved, err := f.resolveShell(step.Cmd)
 		if err != nil {
-			output.PrintStepFail(e.Stderr, label, err)
-			return err
+			return e.finishStep(ctx, label, step.IgnoreError, err)
 		}
-		cmd := ShellCommand(resolved)
-		cmd.Env = e.Env
-		cmd.Stdout = e.Stdout
+		cmd := ShellCommandContext(ctx, resolved, e.GracePeriod)
+		cmd.Env = f.env
+		cmd.Dir = f.dir
+		stdout, captured := captureStdout(step, e.Stdout)
+		cmd.Stdout = stdout
 		cmd.Stderr = e.Stderr
 		cmd.Stdin = os.Stdin
-		if err := cmd.Run(); err != nil {
-			output.PrintStepFail(e.Stderr, label, err)
-			return err
-		}
-		output.PrintStepDone(e.Stderr, label)
-		return nil
+		err = storeCaptures(ctx, step, f, captured, runCommand(ctx, cmd))
+		return e.finishStep(ctx, label, step.IgnoreError, err)
 
 	case step.Ref != "":
 		label := output.StepLabel(step, index)
 		output.PrintStepStart(e.Stderr, label)
-		if err := e.RunTask(step.Ref); err != nil {
-			output.PrintStepFail(e.Stderr, label, err)
-			return err
+		ref := config.Qualify(f.ns(), step.Ref)
+		params, err := e.withParams(ref, step, f)
+		if err != nil {
+			return e.finishStep(ctx, label, step.IgnoreError, err)
 		}
-		output.PrintStepDone(e.Stderr, label)
-		return nil
+		sub, err := e.newFrame(ctx, ref, params, f)
+		if err == nil {
+			err = e.runFrame(ctx, sub)
+		}
+		return e.finishStep(ctx, label, step.IgnoreError, err)
 
 	case len(step.Concurrent) > 0:
-		return e.executeConcurrent(step.Concurrent, params)
+		return e.executeConcurrent(ctx, step, f)
 
 	default:
 		return fmt.Errorf("step has no cmd, ref, or concurrent")
 	}
 }
 
-func (e *Executor) executeConcurrent(steps []config.Step, params map[string]string) error {
-	n := len(steps)
-	label := fmt.Sprintf("concurrent (%d steps)", n)
+// withParams is what a ref step passes to task ref: the caller's params with
+// the step's with values, resolved against the caller's template data, on
+// top. Every with key must be a param declared by ref.
+func (e *Executor) withParams(ref string, step config.Step, f *frame) (map[string]string, error) {
+	if len(step.With) == 0 {
+		return f.params, nil
+	}
+	task, err := e.Config.ResolveTask(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]string, len(f.params)+len(step.With))
+	for k, v := range f.params {
+		params[k] = v
+	}
+	for _, name := range slices.Sorted(maps.Keys(step.With)) {
+		if !slices.ContainsFunc(task.Params, func(p config.Param) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("with: task %q has no parameter %q", ref, name)
+		}
+		v, err := f.resolve(step.With[name])
+		if err != nil {
+			return nil, fmt.Errorf("with %q: %w", name, err)
+		}
+		params[name] = v
+	}
+	return params, nil
+}
+
+func (e *Executor) executeConcurrent(ctx context.Context, step config.Step, f *frame) error {
+	steps := step.Concurrent
+	labels := make([]string, len(steps))
+	for i, s := range steps {
+		labels[i] = output.StepLabel(s, i)
+	}
+
+	label := fmt.Sprintf("concurrent (%d steps)", len(steps))
+	err := e.fanOut(ctx, label, labels, step.FailFast || e.FailFast, step.MaxParallel, func(ctx context.Context, child *Executor, i int) error {
+		return child.executeStep(ctx, steps[i], f, i)
+	})
+	return e.finishStep(ctx, label, step.IgnoreError, err)
+}
+
+// fanOut runs fn once per label in parallel. Each call gets a child Executor
+// whose output is prefixed with its label; errors are collected and joined.
+// fanOut prints the start line for label; the caller prints the outcome.
+// With failFast, the first error cancels the context of the remaining calls.
+// maxParallel, if positive, bounds how many calls run at once.
+func (e *Executor) fanOut(ctx context.Context, label string, labels []string, failFast bool, maxParallel int, fn func(ctx context.Context, child *Executor, i int) error) error {
 	output.PrintStepStart(e.Stderr, label)
 
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
 	// Create serialized writers for atomic output
 	stdoutSerial := output.NewSerialWriter(e.Stdout)
 	stderrSerial := output.NewSerialWriter(e.Stderr)
 
 	var (
-		wg   sync.WaitGroup
-		mu   sync.Mutex
-		errs []error
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		slots chan struct{}
 	)
+	if maxParallel > 0 {
+		slots = make(chan struct{}, maxParallel)
+	}
 
-	for i, step := range steps {
+	for i, stepLabel := range labels {
 		wg.Add(1)
-		go func(s config.Step, idx int) {
+		go func(stepLabel string, idx int) {
 			defer wg.Done()
 
-			stepLabel := output.StepLabel(s, idx)
 			c := output.LabelColor(idx)
 			pw := output.NewPrefixWriter(stdoutSerial, stepLabel, c)
 			pwErr := output.NewPrefixWriter(stderrSerial, stepLabel, c)
+			// Secrets are redacted before the prefix is added, which would
+			// split multi-line ones.
+			stdout := output.NewRedactWriter(pw, e.sched.secrets)
+			stderr := output.NewRedactWriter(pwErr, e.sched.secrets)
 
-			child := &Executor{
-				Config:  e.Config,
-				Env:     e.Env,
-				Params:  e.Params,
-				Stdout:  pw,
-				Stderr:  pwErr,
-				running: e.running,
+			err := acquireSlot(runCtx, slots)
+			if err == nil {
+				err = fn(runCtx, e.child(stdout, stderr), idx)
+				releaseSlot(slots)
+			} else {
+				err = e.child(stdout, stderr).stepFailed(runCtx, stepLabel, err)
 			}
-
-			if err := child.executeStep(s, params, idx); err != nil {
+			if err != nil {
+				if failFast && !errors.Is(err, ErrCancelled) {
+					cancel(ErrCancelled)
+				}
 				mu.Lock()
 				errs = append(errs, fmt.Errorf("%s: %w", stepLabel, err))
 				mu.Unlock()
 			}
 
+			stdout.Flush()
+			stderr.Flush()
 			pw.Flush()
 			pwErr.Flush()
-		}(step, i)
+		}(stepLabel, i)
 	}
 
 	wg.Wait()
@@ -166,13 +548,52 @@ func (e *Executor) executeConcurrent(steps []config.Step, params map[string]stri
 	stdoutSerial.Close()
 	stderrSerial.Close()
 
-	joined := errors.Join(errs...)
-	if joined != nil {
-		output.PrintStepFail(e.Stderr, label, joined)
-	} else {
+	return errors.Join(errs...)
+}
+
+// finishStep prints the outcome of a step and returns the error to propagate.
+// With ignore set, a failure is downgraded to a warning and swallowed, unless
+// the step was interrupted or cancelled.
+func (e *Executor) finishStep(ctx context.Context, label string, ignore bool, err error) error {
+	if err == nil {
 		output.PrintStepDone(e.Stderr, label)
+		return nil
+	}
+	if ignore && !stopped(context.Cause(ctx)) {
+		output.PrintStepWarn(e.Stderr, label, err)
+		return nil
+	}
+	return e.stepFailed(ctx, label, err)
+}
+
+// stopped reports whether err means the run was interrupted or cancelled, as
+// opposed to a step failing on its own.
+func stopped(err error) bool {
+	return errors.Is(err, ErrInterrupted) || errors.Is(err, ErrCancelled)
+}
+
+// stepFailed prints the status line for a step that returned err and returns
+// the error to propagate. A step that failed because gofer was interrupted, or
+// because a fail-fast sibling failed, is reported as interrupted or cancelled
+// rather than failed, and the cancellation cause is propagated instead of the
+// step's own error so callers can tell them apart.
+func (e *Executor) stepFailed(ctx context.Context, label string, err error) error {
+	if ctx.Err() != nil {
+		cause := context.Cause(ctx)
+		switch {
+		case errors.Is(cause, ErrInterrupted):
+			output.PrintStepInterrupted(e.Stderr, label)
+			return cause
+		case errors.Is(cause, ErrCancelled):
+			output.PrintStepCancelled(e.Stderr, label)
+			return cause
+		case errors.As(cause, new(*TimeoutError)):
+			output.PrintStepFail(e.Stderr, label, cause)
+			return cause
+		}
 	}
-	return joined
+	output.PrintStepFail(e.Stderr, label, err)
+	return err
 }
 
 func shouldRun(os string) bool {
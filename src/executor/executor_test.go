@@ -2,10 +2,16 @@ package executor
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azmekk/gofer/config"
 )
@@ -29,7 +35,7 @@ func TestRunTask_SimpleCmd(t *testing.T) {
 		},
 	}
 	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
-	if err := e.RunTask("hello"); err != nil {
+	if err := e.RunTask(context.Background(), "hello"); err != nil {
 		t.Fatal(err)
 	}
 	if !strings.Contains(stdout.String(), "hello world") {
@@ -49,7 +55,7 @@ func TestRunTask_ParamDefault(t *testing.T) {
 		},
 	}
 	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
-	if err := e.RunTask("greet"); err != nil {
+	if err := e.RunTask(context.Background(), "greet"); err != nil {
 		t.Fatal(err)
 	}
 	if !strings.Contains(stdout.String(), "hello world") {
@@ -68,7 +74,7 @@ func TestRunTask_ParamRequired(t *testing.T) {
 		},
 	}
 	e, _, _ := newTestExecutor(cfg, map[string]string{})
-	err := e.RunTask("greet")
+	err := e.RunTask(context.Background(), "greet")
 	if err == nil {
 		t.Fatal("expected error for missing required param")
 	}
@@ -88,7 +94,7 @@ func TestRunTask_ParamProvided(t *testing.T) {
 		},
 	}
 	e, stdout, _ := newTestExecutor(cfg, map[string]string{"name": "alice"})
-	if err := e.RunTask("greet"); err != nil {
+	if err := e.RunTask(context.Background(), "greet"); err != nil {
 		t.Fatal(err)
 	}
 	if !strings.Contains(stdout.String(), "hello alice") {
@@ -110,7 +116,7 @@ func TestRunTask_RefStep(t *testing.T) {
 		},
 	}
 	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
-	if err := e.RunTask("a"); err != nil {
+	if err := e.RunTask(context.Background(), "a"); err != nil {
 		t.Fatal(err)
 	}
 	if !strings.Contains(stdout.String(), "from-b") {
@@ -133,7 +139,7 @@ func TestRunTask_CircularRef(t *testing.T) {
 	}
 	e, _, _ := newTestExecutor(cfg, map[string]string{})
 	e.Stderr = io.Discard
-	err := e.RunTask("a")
+	err := e.RunTask(context.Background(), "a")
 	if err == nil {
 		t.Fatal("expected cycle error")
 	}
@@ -159,7 +165,7 @@ func TestRunTask_ConcurrentSteps(t *testing.T) {
 		},
 	}
 	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
-	if err := e.RunTask("parallel"); err != nil {
+	if err := e.RunTask(context.Background(), "parallel"); err != nil {
 		t.Fatal(err)
 	}
 	out := stdout.String()
@@ -176,7 +182,7 @@ func TestRunTask_UnknownTask(t *testing.T) {
 		Tasks: map[string]config.Task{},
 	}
 	e, _, _ := newTestExecutor(cfg, map[string]string{})
-	err := e.RunTask("nonexistent")
+	err := e.RunTask(context.Background(), "nonexistent")
 	if err == nil {
 		t.Fatal("expected error for unknown task")
 	}
@@ -203,7 +209,7 @@ func TestRunTask_OSFiltering(t *testing.T) {
 		},
 	}
 	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
-	if err := e.RunTask("filtered"); err != nil {
+	if err := e.RunTask(context.Background(), "filtered"); err != nil {
 		t.Fatal(err)
 	}
 	if strings.Contains(stdout.String(), "should-not-run") {
@@ -222,8 +228,1228 @@ func TestRunTask_EmptyStep(t *testing.T) {
 	}
 	e, _, _ := newTestExecutor(cfg, map[string]string{})
 	e.Stderr = io.Discard
-	err := e.RunTask("empty")
+	err := e.RunTask(context.Background(), "empty")
 	if err == nil {
 		t.Fatal("expected error for empty step")
 	}
 }
+
+func TestRunTask_DepsRunOnce(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"build": {Desc: "build", Steps: []config.Step{{Cmd: "echo build-ran"}}},
+			"test":  {Desc: "test", Deps: []string{"build"}, Steps: []config.Step{{Cmd: "echo test-ran"}}},
+			"lint":  {Desc: "lint", Deps: []string{"build"}, Steps: []config.Step{{Cmd: "echo lint-ran"}}},
+			"all":   {Desc: "all", Deps: []string{"test", "lint"}, Steps: []config.Step{{Cmd: "echo all-ran"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "all"); err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	if n := strings.Count(out, "build-ran"); n != 1 {
+		t.Errorf("build ran %d times, want 1: %q", n, out)
+	}
+	for _, want := range []string{"test-ran", "lint-ran", "all-ran"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stdout missing %q: %q", want, out)
+		}
+	}
+	if strings.Index(out, "all-ran") < strings.Index(out, "test-ran") {
+		t.Errorf("task steps ran before its deps: %q", out)
+	}
+}
+
+func TestRunTask_DepsDistinctParams(t *testing.T) {
+	def := "debug"
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"build": {
+				Desc:   "build",
+				Params: []config.Param{{Name: "mode", Default: &def}},
+				Steps:  []config.Step{{Cmd: "echo build-{{.mode}}"}},
+			},
+			"a": {Desc: "a", Deps: []string{"build"}, Steps: []config.Step{{Cmd: "echo a"}}},
+			"b": {Desc: "b", Steps: []config.Step{{Ref: "a"}, {Ref: "a"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "b"); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(stdout.String(), "build-debug"); n != 1 {
+		t.Errorf("build ran %d times, want 1: %q", n, stdout.String())
+	}
+}
+
+func TestRunTask_DepFailure(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"bad":  {Desc: "bad", Steps: []config.Step{{Cmd: "exit 1"}}},
+			"main": {Desc: "main", Deps: []string{"bad"}, Steps: []config.Step{{Cmd: "echo main-ran"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "main"); err == nil {
+		t.Fatal("expected error from failing dep")
+	}
+	if strings.Contains(stdout.String(), "main-ran") {
+		t.Error("task steps should not run after a dep fails")
+	}
+}
+
+func TestRunTask_DepsCycle(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"a": {Desc: "a", Deps: []string{"b"}, Steps: []config.Step{{Cmd: "echo a-ran"}}},
+			"b": {Desc: "b", Steps: []config.Step{{Concurrent: []config.Step{{Ref: "a"}}}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "a")
+	if err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if !strings.Contains(err.Error(), "cycle detected: a -> b -> a") {
+		t.Errorf("error = %q, want 'cycle detected: a -> b -> a'", err.Error())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("nothing should run when a cycle is detected, got %q", stdout.String())
+	}
+}
+
+func TestRunTask_IncludedNamespace(t *testing.T) {
+	dir := t.TempDir()
+	backend := &config.GoferConfig{
+		EnvFile: config.EnvFiles{config.DefaultEnvFile},
+		Dir:     dir,
+		Tasks: map[string]config.Task{
+			"gen":   {Desc: "gen", Steps: []config.Step{{Cmd: "echo gen-ran"}}},
+			"build": {Desc: "build", Deps: []string{"gen"}, Steps: []config.Step{{Ref: "lint"}, {Cmd: "echo build-ran"}}},
+			"lint":  {Desc: "lint", Steps: []config.Step{{Cmd: "echo lint-ran"}}},
+		},
+	}
+	cfg := &config.GoferConfig{
+		Includes: map[string]string{"backend": "backend/gofer.json"},
+		Included: map[string]*config.GoferConfig{"backend": backend},
+		Tasks: map[string]config.Task{
+			"all": {Desc: "all", Steps: []config.Step{{Ref: "backend.build"}}},
+			// A root task with the same name must not shadow the included one.
+			"lint": {Desc: "root lint", Steps: []config.Step{{Cmd: "echo root-lint-ran"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "all"); err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	for _, want := range []string{"gen-ran", "lint-ran", "build-ran"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stdout missing %q: %q", want, out)
+		}
+	}
+	if strings.Contains(out, "root-lint-ran") {
+		t.Errorf("ref inside included task resolved against the root config: %q", out)
+	}
+}
+
+func TestRunTask_ConcurrentFailFast(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"parallel": {
+				Desc: "run parallel",
+				Steps: []config.Step{
+					{
+						FailFast: true,
+						Concurrent: []config.Step{
+							{Name: "bad", Cmd: "exit 3"},
+							{Name: "slow", Cmd: "sleep 10"},
+						},
+					},
+				},
+			},
+		},
+	}
+	e, _, stderr := newTestExecutor(cfg, map[string]string{})
+	start := time.Now()
+	err := e.RunTask(context.Background(), "parallel")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("fail_fast did not cancel the slow sibling")
+	}
+	if !strings.Contains(err.Error(), "slow: cancelled") {
+		t.Errorf("error = %q, want 'slow: cancelled'", err.Error())
+	}
+	if !strings.Contains(err.Error(), "bad: exit status 3") {
+		t.Errorf("error = %q, want the original failure", err.Error())
+	}
+	if !strings.Contains(stderr.String(), "slow: cancelled") {
+		t.Errorf("stderr = %q, want cancelled status line", stderr.String())
+	}
+}
+
+func TestRunTask_ConcurrentWithoutFailFast(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"parallel": {
+				Desc: "run parallel",
+				Steps: []config.Step{
+					{
+						Concurrent: []config.Step{
+							{Name: "bad", Cmd: "exit 3"},
+							{Name: "good", Cmd: "echo good-ran"},
+						},
+					},
+				},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "parallel"); err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(stdout.String(), "good-ran") {
+		t.Errorf("sibling should run to completion without fail_fast: %q", stdout.String())
+	}
+}
+
+func sleepyConcurrent(maxParallel int) *config.GoferConfig {
+	var steps []config.Step
+	for i := 0; i < 4; i++ {
+		steps = append(steps, config.Step{Cmd: "sleep 0.3"})
+	}
+	return &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"parallel": {
+				Desc:  "run parallel",
+				Steps: []config.Step{{MaxParallel: maxParallel, Concurrent: steps}},
+			},
+		},
+	}
+}
+
+func TestRunTask_ConcurrentMaxParallel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	e, _, _ := newTestExecutor(sleepyConcurrent(2), map[string]string{})
+	start := time.Now()
+	if err := e.RunTask(context.Background(), "parallel"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 600*time.Millisecond {
+		t.Errorf("4 steps of 0.3s with max_parallel=2 took %s, want >= 600ms", elapsed)
+	}
+}
+
+func TestRunTask_Jobs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	cfg := sleepyConcurrent(0)
+	// Nest a second block so the limit has to hold across levels.
+	task := cfg.Tasks["parallel"]
+	task.Steps = []config.Step{{Concurrent: []config.Step{task.Steps[0], {Cmd: "sleep 0.3"}}}}
+	cfg.Tasks["parallel"] = task
+
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	e.Jobs = 1
+	start := time.Now()
+	if err := e.RunTask(context.Background(), "parallel"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 1500*time.Millisecond {
+		t.Errorf("5 steps of 0.3s with -j 1 took %s, want >= 1.5s", elapsed)
+	}
+}
+
+func TestRunTask_IgnoreError(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"cleanup": {
+				Desc: "cleanup",
+				Steps: []config.Step{
+					{Name: "rm", Cmd: "exit 1", IgnoreError: true},
+					{Cmd: "echo after-ran"},
+				},
+			},
+		},
+	}
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "cleanup"); err != nil {
+		t.Fatalf("ignored failure should not fail the task: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "after-ran") {
+		t.Errorf("later steps should still run: %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "⚠ rm: exit status 1 (ignored)") {
+		t.Errorf("stderr = %q, want warning line", stderr.String())
+	}
+	if strings.Contains(stderr.String(), "✗") {
+		t.Errorf("ignored failure should not print a failure line: %q", stderr.String())
+	}
+}
+
+func TestRunTask_IgnoreErrorAfterRetries(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"flaky": {
+				Desc: "flaky",
+				Steps: []config.Step{
+					{Name: "lint", Cmd: "exit 1", IgnoreError: true, Retry: &config.Retry{Count: 1, Delay: "1ms"}},
+				},
+			},
+		},
+	}
+	e, _, stderr := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "flaky"); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(stderr.String(), "▸ lint"); n != 2 {
+		t.Errorf("step started %d times, want 2 (ignore_error must not prevent retries): %q", n, stderr.String())
+	}
+}
+
+func TestRunTask_ContinueOnError(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"checks": {
+				Desc:            "checks",
+				ContinueOnError: true,
+				Steps: []config.Step{
+					{Name: "first", Cmd: "exit 1"},
+					{Cmd: "echo second-ran"},
+					{Name: "third", Cmd: "exit 2"},
+				},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "checks")
+	if err == nil {
+		t.Fatal("continue_on_error should still fail the task")
+	}
+	if !strings.Contains(stdout.String(), "second-ran") {
+		t.Errorf("steps after a failure should run: %q", stdout.String())
+	}
+	for _, want := range []string{"exit status 1", "exit status 2"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to contain %q", err.Error(), want)
+		}
+	}
+}
+
+func TestRunTask_FinallyAfterFailure(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"itest": {
+				Desc: "itest",
+				Steps: []config.Step{
+					{Name: "start db", Cmd: "echo db-started"},
+					{Name: "test", Cmd: "exit 3"},
+					{Cmd: "echo should-not-run"},
+				},
+				Finally: []config.Step{
+					{Name: "stop server", Cmd: "exit 4"},
+					{Name: "stop db", Cmd: "echo db-stopped"},
+				},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "itest")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	out := stdout.String()
+	if strings.Contains(out, "should-not-run") {
+		t.Errorf("steps after the failure should not run: %q", out)
+	}
+	if !strings.Contains(out, "db-stopped") {
+		t.Errorf("every finally step should run: %q", out)
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "exit status 3") || !strings.Contains(msg, "exit status 4") {
+		t.Errorf("error = %q, want both the step and the cleanup error", msg)
+	}
+	if strings.Index(msg, "exit status 3") > strings.Index(msg, "exit status 4") {
+		t.Errorf("original error should come first: %q", msg)
+	}
+}
+
+func TestRunTask_FinallyAfterSuccess(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"t": {
+				Desc:    "t",
+				Steps:   []config.Step{{Cmd: "echo main"}},
+				Finally: []config.Step{{Cmd: "echo cleanup"}},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "t"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "main\ncleanup\n" {
+		t.Errorf("stdout = %q, want %q", got, "main\ncleanup\n")
+	}
+}
+
+func TestRunTask_IfCondition(t *testing.T) {
+	def := "debug"
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"build": {
+				Desc:   "build",
+				Params: []config.Param{{Name: "mode", Default: &def}},
+				Steps: []config.Step{
+					{Name: "strip", Cmd: "echo stripping", If: `{{ eq .mode "release" }}`},
+					{Name: "debug info", Cmd: "echo debug-info", If: `{{ ne .mode "release" }}`},
+					{Name: "env check", Cmd: "echo ci-only", If: `{{ .ENV.GOFER_TEST_CI }}`},
+					{Name: "unset env", Cmd: "echo unset-ran", If: `{{ index .ENV "GOFER_TEST_UNSET" }}`},
+					{Name: "os check", Cmd: "echo os-ok", If: `{{ eq .OS "` + runtime.GOOS + `" }}`},
+				},
+			},
+		},
+	}
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{})
+	e.Env = []string{"GOFER_TEST_CI=0"}
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "debug-info\nos-ok\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	for _, want := range []string{"↷ strip (condition not met)", "↷ env check (condition not met)"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q, want %q", stderr.String(), want)
+		}
+	}
+
+	e, stdout, _ = newTestExecutor(cfg, map[string]string{"mode": "release"})
+	e.Env = []string{"GOFER_TEST_CI=true"}
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "stripping\nci-only\nos-ok\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestRunTask_IfCmd(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"t": {
+				Desc: "t",
+				Steps: []config.Step{
+					{Name: "yes", Cmd: "echo yes-ran", IfCmd: "exit 0"},
+					{Name: "no", Cmd: "echo no-ran", IfCmd: "exit 1"},
+					{Name: "both", Cmd: "echo both-ran", If: "true", IfCmd: "exit 1"},
+				},
+			},
+		},
+	}
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "t"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "yes-ran\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if !strings.Contains(stderr.String(), "↷ no (condition not met)") {
+		t.Errorf("stderr = %q, want skip line", stderr.String())
+	}
+}
+
+func TestRunTask_IfTemplateError(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"t": {Desc: "t", Steps: []config.Step{{Name: "s", Cmd: "echo", If: "{{ .nope }}"}}},
+		},
+	}
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "t")
+	if err == nil || !strings.Contains(err.Error(), "if:") {
+		t.Errorf("err = %v, want an if template error", err)
+	}
+}
+
+func TestRunTask_RefParamValidation(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"deploy": {
+				Desc:   "deploy",
+				Params: []config.Param{{Name: "replicas", Type: "int"}},
+				Steps:  []config.Step{{Cmd: "echo deploying {{.replicas}}"}},
+			},
+			"release": {Desc: "release", Steps: []config.Step{{Ref: "deploy", With: map[string]string{"replicas": "two"}}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "release")
+	if err == nil || !strings.Contains(err.Error(), `task "deploy": param "replicas": "two" is not an integer`) {
+		t.Fatalf("err = %v, want a param validation error", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("nothing should run with an invalid param, got %q", stdout.String())
+	}
+}
+
+func TestRunTask_SecretParamValidation(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"login": {
+				Desc:   "login",
+				Params: []config.Param{{Name: "token", Pattern: `[a-z]+`, Secret: true}},
+				Steps:  []config.Step{{Cmd: "echo logging in"}},
+			},
+			"release": {Desc: "release", Steps: []config.Step{{Ref: "login", With: map[string]string{"token": "Hunter2Secret"}}}},
+		},
+	}
+	for _, ref := range []string{"login", "release"} {
+		e, _, stderr := newTestExecutor(cfg, map[string]string{"token": "Hunter2Secret"})
+		err := e.RunTask(context.Background(), ref)
+		if err == nil || !strings.Contains(err.Error(), `param "token": value does not match pattern`) {
+			t.Fatalf("%s: err = %v, want a param validation error", ref, err)
+		}
+		if out := stderr.String() + err.Error(); strings.Contains(out, "Hunter2Secret") {
+			t.Errorf("%s: output shows the secret:\n%s", ref, out)
+		}
+	}
+}
+
+func TestRunTask_PromptForMissingParams(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"deploy": {
+				Desc:   "deploy",
+				Params: []config.Param{{Name: "env"}},
+				Steps:  []config.Step{{Cmd: "echo deploying {{.env}}"}},
+			},
+			"release": {
+				Desc: "release",
+				Steps: []config.Step{{Concurrent: []config.Step{
+					{Name: "a", Ref: "deploy"},
+					{Name: "b", Ref: "deploy"},
+				}}},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	var asked []string
+	e.Prompt = func(ctx context.Context, ref string, p config.Param) (string, error) {
+		asked = append(asked, ref+"."+p.Name)
+		return "staging", nil
+	}
+	if err := e.RunTask(context.Background(), "release"); err != nil {
+		t.Fatal(err)
+	}
+	if len(asked) != 1 || asked[0] != "deploy.env" {
+		t.Errorf("asked = %v, want a single prompt for deploy.env", asked)
+	}
+	if n := strings.Count(stdout.String(), "deploying staging"); n != 2 {
+		t.Errorf("stdout = %q, want the prompted value used by both refs", stdout.String())
+	}
+}
+
+func TestRunTask_PromptError(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"deploy": {Desc: "deploy", Params: []config.Param{{Name: "env"}}, Steps: []config.Step{{Cmd: "echo"}}},
+		},
+	}
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	e.Prompt = func(ctx context.Context, ref string, p config.Param) (string, error) {
+		return "", io.EOF
+	}
+	err := e.RunTask(context.Background(), "deploy")
+	if !errors.Is(err, io.EOF) || !strings.Contains(err.Error(), `parameter "env"`) {
+		t.Errorf("err = %v, want the prompt error naming the param", err)
+	}
+}
+
+func TestRunTask_RefWith(t *testing.T) {
+	def := "bin/app"
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"compile": {
+				Desc:   "compile",
+				Params: []config.Param{{Name: "output", Default: &def}, {Name: "goos"}},
+				Steps:  []config.Step{{Cmd: "echo {{.goos}} {{.output}}"}},
+			},
+			"dist": {
+				Desc:   "dist",
+				Params: []config.Param{{Name: "version"}, {Name: "goos"}},
+				Steps: []config.Step{
+					{Ref: "compile", With: map[string]string{"goos": "linux", "output": "dist/app-{{.version}}-linux"}},
+					{Ref: "compile", With: map[string]string{"goos": "darwin", "output": "dist/app-{{.version}}-darwin"}},
+					{Ref: "compile"},
+				},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"version": "1.2", "goos": "windows"})
+	if err := e.RunTask(context.Background(), "dist"); err != nil {
+		t.Fatal(err)
+	}
+	want := "linux dist/app-1.2-linux\ndarwin dist/app-1.2-darwin\nwindows bin/app\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestRunTask_RefSeesOnlyDeclaredParams(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"inner": {Desc: "inner", Steps: []config.Step{{Cmd: "echo {{.token}}"}}},
+			"outer": {
+				Desc:   "outer",
+				Params: []config.Param{{Name: "token"}},
+				Steps:  []config.Step{{Ref: "inner"}},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"token": "abc"})
+	err := e.RunTask(context.Background(), "outer")
+	if err == nil || !strings.Contains(err.Error(), `"token"`) {
+		t.Fatalf("err = %v, want undeclared param to be invisible to the ref'd task", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing", stdout.String())
+	}
+}
+
+func TestRunTask_RefWithUnknownParam(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"compile": {Desc: "compile", Steps: []config.Step{{Cmd: "echo"}}},
+			"dist":    {Desc: "dist", Steps: []config.Step{{Ref: "compile", With: map[string]string{"ouput": "x"}}}},
+		},
+	}
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "dist")
+	if err == nil || !strings.Contains(err.Error(), `task "compile" has no parameter "ouput"`) {
+		t.Errorf("err = %v, want unknown with key error", err)
+	}
+}
+
+func TestPositionalParams(t *testing.T) {
+	task := &config.Task{Params: []config.Param{{Name: "pkg"}, {Name: "files", Variadic: true}}}
+	got, err := PositionalParams("t", task, []string{"./api", "a.go", "my file.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["pkg"] != "./api" || got["files"] != "a.go 'my file.go'" {
+		t.Errorf("params = %v", got)
+	}
+
+	got, err = PositionalParams("t", task, []string{"./api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["files"]; ok {
+		t.Errorf("variadic param without args should be left to its default: %v", got)
+	}
+
+	typed := &config.Task{Params: []config.Param{{Name: "ports", Type: "int", Variadic: true}}}
+	if _, err := PositionalParams("t", typed, []string{"80", "http"}); err == nil || !strings.Contains(err.Error(), `"http" is not an integer`) {
+		t.Errorf("err = %v, want each variadic arg validated", err)
+	}
+
+	fixed := &config.Task{Params: []config.Param{{Name: "name"}}}
+	_, err = PositionalParams("hello", fixed, []string{"world", "extra"})
+	if err == nil || !strings.Contains(err.Error(), "unexpected extra") {
+		t.Errorf("err = %v, want an unexpected argument error", err)
+	}
+}
+
+func TestRunTask_PassthroughArgs(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"test": {Desc: "test", Steps: []config.Step{{Ref: "go-test"}}},
+			"go-test": {Desc: "go test", Steps: []config.Step{
+				{Cmd: "printf '<%s>' {{.ARGS}}; echo"},
+			}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	e.Args = []string{"-run", "TestFoo|TestBar", "it's"}
+	if err := e.RunTask(context.Background(), "test"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "<-run><TestFoo|TestBar><it's>\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestRunTask_Vars(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Vars: map[string]config.Var{
+			"version": {Sh: "echo '  1.4.2  '"},
+			"image":   {Value: "registry/app:{{.version}}"},
+			"unused":  {Sh: "echo unused-ran >&2; exit 1"},
+		},
+		Tasks: map[string]config.Task{
+			"build": {
+				Desc:   "build",
+				Params: []config.Param{{Name: "tag"}},
+				Vars:   map[string]config.Var{"image": {Value: "local/app:{{.tag}}-{{.version}}"}},
+				Steps:  []config.Step{{Cmd: "echo {{.image}}"}, {Ref: "push"}},
+			},
+			"push": {
+				Desc:  "push",
+				Steps: []config.Step{{Cmd: "echo pushing {{.image}}"}},
+			},
+		},
+	}
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{"tag": "dev"})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "local/app:dev-1.4.2\npushing registry/app:1.4.2\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if strings.Contains(stderr.String(), "unused-ran") {
+		t.Errorf("unreferenced vars should not be evaluated: %q", stderr.String())
+	}
+}
+
+func TestRunTask_VarsShellRunsOnce(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+	cfg := &config.GoferConfig{
+		Vars: map[string]config.Var{"sha": {Sh: "echo x >> " + counter + "; echo abc123"}},
+		Tasks: map[string]config.Task{
+			"a":   {Desc: "a", Steps: []config.Step{{Cmd: "echo a-{{.sha}}"}}},
+			"b":   {Desc: "b", Steps: []config.Step{{Cmd: "echo b-{{.sha}}"}}},
+			"all": {Desc: "all", Steps: []config.Step{{Ref: "a"}, {Ref: "b"}, {Ref: "a"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "all"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "a-abc123\nb-abc123\na-abc123\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("var command ran %d times, want 1", n)
+	}
+}
+
+func TestRunTask_VarError(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"t": {
+				Desc:  "t",
+				Vars:  map[string]config.Var{"sha": {Sh: "exit 3"}},
+				Steps: []config.Step{{Cmd: "echo first-step"}, {Cmd: "echo {{.sha}}"}},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "t")
+	if err == nil || !strings.Contains(err.Error(), `task "t": var "sha"`) || !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("err = %v, want an error naming the var", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("no step should run when a var fails, got %q", stdout.String())
+	}
+}
+
+func TestRunTask_VarCycle(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"t": {
+				Desc:  "t",
+				Vars:  map[string]config.Var{"a": {Value: "{{.b}}"}, "b": {Value: "{{.a}}"}},
+				Steps: []config.Step{{Cmd: "echo {{.a}}"}},
+			},
+		},
+	}
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	err := e.RunTask(context.Background(), "t")
+	if err == nil || !strings.Contains(err.Error(), "refers to itself") {
+		t.Errorf("err = %v, want a var cycle error", err)
+	}
+}
+
+func TestRunTask_Capture(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"release": {
+				Desc: "release",
+				Steps: []config.Step{
+					{Cmd: "echo '  v1.2.3  '", Capture: "version"},
+					{Cmd: "echo hidden; exit 2", Capture: "probe", CaptureExit: "probe_code", Silent: true},
+					{Cmd: "echo tag {{.version}} probe={{.probe}} code={{.probe_code}}"},
+					{Ref: "publish"},
+				},
+			},
+			"publish": {Desc: "publish", Steps: []config.Step{{Cmd: "echo publishing {{.version}}"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "release"); err != nil {
+		t.Fatal(err)
+	}
+	want := "  v1.2.3  \ntag v1.2.3 probe=hidden code=2\npublishing v1.2.3\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestRunTask_CaptureInConcurrentBlock(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"t": {
+				Desc: "t",
+				Steps: []config.Step{
+					{Concurrent: []config.Step{
+						{Name: "a", Cmd: "echo one", Capture: "a", Silent: true},
+						{Name: "b", Cmd: "echo two", Capture: "b", Silent: true},
+					}},
+					{Cmd: "echo {{.a}}-{{.b}}"},
+				},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "t"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "one-two\n" {
+		t.Errorf("stdout = %q, want %q", got, "one-two\n")
+	}
+}
+
+func TestRunTask_CaptureFailure(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"t": {Desc: "t", Steps: []config.Step{{Cmd: "echo partial; exit 1", Capture: "out"}}},
+		},
+	}
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "t"); err == nil {
+		t.Error("a failing capture step without capture_exit should fail")
+	}
+}
+
+func TestRunTask_EscapeAuto(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Escape: config.EscapeAuto,
+		Vars:   map[string]config.Var{"greeting": {Sh: "echo {{.msg}}"}},
+		Tasks: map[string]config.Task{
+			"say": {
+				Desc:   "say",
+				Params: []config.Param{{Name: "msg"}, {Name: "words"}},
+				Steps: []config.Step{
+					{Cmd: "printf '%s|' {{.msg}} {{.greeting}} {{ .words | raw }}"},
+				},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"msg": "it's   $HOME; done", "words": "a b"})
+	if err := e.RunTask(context.Background(), "say"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "it's   $HOME; done|it's   $HOME; done|a|b|"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestRunTask_VariadicValidatedFromAnySource(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"ports": {
+				Desc:   "ports",
+				Params: []config.Param{{Name: "ports", Type: "int", Variadic: true}},
+				Steps:  []config.Step{{Cmd: "echo {{.ports}}"}},
+			},
+			"caller": {Desc: "caller", Steps: []config.Step{{Ref: "ports", With: map[string]string{"ports": "80 http"}}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"ports": "80 x; echo INJECTED"})
+	if err := e.RunTask(context.Background(), "ports"); err == nil || !strings.Contains(err.Error(), `"x;" is not an integer`) {
+		t.Errorf("err = %v, want an int validation error", err)
+	}
+	if strings.Contains(stdout.String(), "INJECTED") {
+		t.Errorf("invalid variadic value ran: %q", stdout.String())
+	}
+
+	e, _, _ = newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "caller"); err == nil || !strings.Contains(err.Error(), `"http" is not an integer`) {
+		t.Errorf("err = %v, want with values validated", err)
+	}
+}
+
+func TestRunTask_VariadicQuotedFromAnySource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	cfg := &config.GoferConfig{
+		Escape: config.EscapeAuto,
+		Tasks: map[string]config.Task{
+			"t": {
+				Desc:   "t",
+				Params: []config.Param{{Name: "files", Variadic: true}},
+				Steps:  []config.Step{{Cmd: "printf '%s|' {{.files}}; echo"}},
+			},
+			"caller": {
+				Desc:   "caller",
+				Params: []config.Param{{Name: "v"}},
+				Steps:  []config.Step{{Ref: "t", With: map[string]string{"files": "{{.v}}; echo INJECTED"}}},
+			},
+		},
+	}
+
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"files": "x; echo INJECTED"})
+	if err := e.RunTask(context.Background(), "t"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "x;|echo|INJECTED|\n"; got != want {
+		t.Errorf("-p value: stdout = %q, want %q", got, want)
+	}
+
+	e, stdout, _ = newTestExecutor(cfg, map[string]string{"v": "a"})
+	if err := e.RunTask(context.Background(), "caller"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "a;|echo|INJECTED|\n"; got != want {
+		t.Errorf("with value: stdout = %q, want %q", got, want)
+	}
+}
+
+func TestRunTask_Dir(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"web/src", "api"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.GoferConfig{
+		Dir: root,
+		Tasks: map[string]config.Task{
+			"build": {
+				Desc:   "build",
+				Params: []config.Param{{Name: "app"}},
+				Dir:    "{{.app}}",
+				Steps: []config.Step{
+					{Cmd: "basename \"$(pwd)\""},
+					{Cmd: "basename \"$(pwd)\"", Dir: "web/src"},
+					{Concurrent: []config.Step{{Cmd: "basename \"$(pwd)\""}}, Dir: "api"},
+					{Cmd: "basename \"$(pwd)\""},
+				},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"app": "web"})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	got := stdout.String()
+	if !strings.HasPrefix(got, "web\nsrc\n") || !strings.Contains(got, "] api") || !strings.HasSuffix(got, "\nweb\n") {
+		t.Errorf("stdout = %q, want web, src, api, web", got)
+	}
+}
+
+func TestRunTask_DirMissing(t *testing.T) {
+	root := t.TempDir()
+	cfg := &config.GoferConfig{
+		Dir: root,
+		Tasks: map[string]config.Task{
+			"task": {Desc: "task", Dir: "nope", Steps: []config.Step{{Cmd: "echo ran"}}},
+			"step": {Desc: "step", Steps: []config.Step{{Cmd: "echo ran", Dir: "nope"}}},
+		},
+	}
+	for _, name := range []string{"task", "step"} {
+		e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+		err := e.RunTask(context.Background(), name)
+		if err == nil || !strings.Contains(err.Error(), "does not exist") {
+			t.Errorf("%s: err = %v, want a missing dir error", name, err)
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("%s: command ran despite missing dir: %q", name, stdout.String())
+		}
+	}
+}
+
+func TestRunTask_Env(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".env.build"), []byte("MODE=file\nFROM_FILE=yes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.GoferConfig{
+		Dir: root,
+		Tasks: map[string]config.Task{
+			"build": {
+				Desc:    "build",
+				Params:  []config.Param{{Name: "mode"}},
+				EnvFile: config.EnvFiles{".env.build"},
+				Env:     map[string]string{"MODE": "{{.mode}}", "LEVEL": "task"},
+				Steps: []config.Step{
+					{Cmd: "echo $MODE $FROM_FILE $LEVEL"},
+					{Cmd: "echo $LEVEL {{.ENV.LEVEL}}", Env: map[string]string{"LEVEL": "step"}},
+					{Ref: "child"},
+					{Ref: "child", Env: map[string]string{"LEVEL": "ref"}},
+				},
+			},
+			"child": {Desc: "child", Steps: []config.Step{{Cmd: "echo child $MODE $LEVEL"}}},
+			"other": {Desc: "other", Steps: []config.Step{{Cmd: "echo other ${LEVEL:-unset}"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"mode": "production"})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	want := "production yes task\nstep step\nchild production task\nchild production ref\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+
+	e, stdout, _ = newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "other"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "other unset\n" {
+		t.Errorf("env leaked into another task: %q", got)
+	}
+}
+
+func TestRunTask_EnvPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envFile, []byte("TOKEN=placeholder\nREGION=file\nONLY_FILE=yes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	host := []string{"PATH=" + os.Getenv("PATH"), "TOKEN=real", "REGION=host"}
+	steps := []config.Step{{Cmd: "echo $TOKEN $REGION $ONLY_FILE"}}
+
+	tests := []struct {
+		precedence string
+		keys       map[string]string
+		want       string
+	}{
+		{"", nil, "placeholder file yes\n"},
+		{config.EnvPrecedenceHost, nil, "real host yes\n"},
+		{config.EnvPrecedenceHost, map[string]string{"REGION": config.EnvPrecedenceFile}, "real file yes\n"},
+		{config.EnvPrecedenceFile, map[string]string{"TOKEN": config.EnvPrecedenceHost}, "real file yes\n"},
+	}
+	for _, tt := range tests {
+		cfg := &config.GoferConfig{
+			EnvFile:           config.EnvFiles{envFile},
+			EnvPrecedence:     tt.precedence,
+			EnvPrecedenceKeys: tt.keys,
+			Tasks:             map[string]config.Task{"t": {Desc: "t", Steps: steps}},
+		}
+		e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+		e.Env = host
+		if err := e.RunTask(context.Background(), "t"); err != nil {
+			t.Fatal(err)
+		}
+		if got := stdout.String(); got != tt.want {
+			t.Errorf("precedence %q %v: stdout = %q, want %q", tt.precedence, tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestRunTask_EnvRequired(t *testing.T) {
+	cfg := &config.GoferConfig{
+		EnvRequired: []string{"TOKEN"},
+		Tasks: map[string]config.Task{
+			"deploy": {
+				Desc:        "deploy",
+				Deps:        []string{"build"},
+				EnvRequired: []string{"REGION", "EMPTY"},
+				Steps:       []config.Step{{Cmd: "echo deploying"}},
+			},
+			"build": {Desc: "build", Steps: []config.Step{{Cmd: "echo building"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	e.Env = []string{"EMPTY="}
+	err := e.RunTask(context.Background(), "deploy")
+	if err == nil || !strings.Contains(err.Error(), `task "build": missing required environment variable(s) TOKEN`) {
+		t.Fatalf("err = %v", err)
+	}
+	if stdout.Len() > 0 {
+		t.Errorf("nothing should run before the check: %q", stdout.String())
+	}
+
+	e, stdout, _ = newTestExecutor(cfg, map[string]string{})
+	e.Env = []string{"TOKEN=t", "EMPTY="}
+	err = e.RunTask(context.Background(), "deploy")
+	if err == nil || !strings.Contains(err.Error(), `task "deploy": missing required environment variable(s) REGION, EMPTY`) {
+		t.Fatalf("err = %v", err)
+	}
+	if got := stdout.String(); got != "building\n" {
+		t.Errorf("only deps should run before the check: %q", got)
+	}
+
+	// The task's own env counts.
+	deploy := cfg.Tasks["deploy"]
+	deploy.Env = map[string]string{"EMPTY": "x"}
+	cfg.Tasks["deploy"] = deploy
+	e, stdout, _ = newTestExecutor(cfg, map[string]string{})
+	e.Env = []string{"REGION=eu", "EMPTY=", "TOKEN=t"}
+	if err := e.RunTask(context.Background(), "deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "building\ndeploying\n" {
+		t.Errorf("stdout = %q", got)
+	}
+}
+
+func TestRunTask_EnvClear(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"hermetic": {
+				Desc:     "hermetic",
+				EnvClear: true,
+				EnvAllow: []string{"PATH", "LC_*"},
+				Env:      map[string]string{"MODE": "ci"},
+				Steps:    []config.Step{{Cmd: "echo ${HOME:-no-home} $LC_ALL $MODE"}},
+			},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	e.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=/home/me", "LC_ALL=C"}
+	if err := e.RunTask(context.Background(), "hermetic"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "no-home C ci\n" {
+		t.Errorf("stdout = %q", got)
+	}
+}
+
+func TestRunTask_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("API_TOKEN=tok-123456\nREGION=eu-west-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.GoferConfig{
+		EnvFile:   config.EnvFiles{filepath.Join(dir, ".env")},
+		EnvSecret: []string{"*_TOKEN"},
+		Tasks: map[string]config.Task{
+			"deploy": {
+				Desc:   "deploy",
+				Params: []config.Param{{Name: "password", Secret: true}},
+				Steps: []config.Step{
+					{Cmd: "echo token=$API_TOKEN region=$REGION"},
+					{Cmd: "printf 'pass=hun'; printf 'ter2\n'"},
+					{Concurrent: []config.Step{
+						{Name: "a", Cmd: "echo a {{.password}}"},
+						{Name: "b", Cmd: "echo b $API_TOKEN"},
+					}},
+					{Cmd: "cat {{readFile .password}}"},
+				},
+			},
+		},
+	}
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{"password": "hunter2"})
+	err := e.RunTask(context.Background(), "deploy")
+	if err == nil {
+		t.Fatal("expected the last step to fail")
+	}
+	out := stdout.String() + stderr.String() + err.Error()
+	for _, secret := range []string{"tok-123456", "hunter2"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("output contains %q:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"token=*** region=eu-west-1\n", "pass=***\n", "[a] a ***", "[b] b ***"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if e.Stdout != stdout || e.Stderr != stderr {
+		t.Error("RunTask didn't restore the executor's writers")
+	}
+}
+
+func TestTaskEnv(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("TOKEN=file\nREGION=file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env.prod"), []byte("REGION=prod\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.GoferConfig{
+		Dir:      dir,
+		EnvFile:  config.EnvFiles{filepath.Join(dir, ".env")},
+		Profiles: map[string]config.Profile{"prod": {EnvFile: config.EnvFiles{filepath.Join(dir, ".env.prod")}}},
+		Tasks: map[string]config.Task{
+			"build": {
+				Desc:   "build",
+				Params: []config.Param{{Name: "mode"}},
+				Env:    map[string]string{"MODE": "{{.mode}}"},
+				Steps: []config.Step{
+					{Name: "compile", Cmd: "echo", Env: map[string]string{"CGO_ENABLED": "0", "OUT": "{{.out}}"}},
+					{Concurrent: []config.Step{{Name: "lint", Cmd: "echo", Env: map[string]string{"LEVEL": "lint"}}}},
+				},
+			},
+		},
+	}
+	if err := cfg.ApplyProfile("prod"); err != nil {
+		t.Fatal(err)
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{"mode": "release"})
+	e.Env = []string{"HOME=/home/me"}
+	vars, steps, err := e.TaskEnv(context.Background(), "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() > 0 {
+		t.Errorf("TaskEnv ran steps: %q", stdout.String())
+	}
+
+	var got []string
+	for _, v := range vars {
+		got = append(got, v.Name+"="+v.Value+" <"+v.Source+">")
+	}
+	prodFile := filepath.Join(dir, ".env.prod")
+	want := []string{
+		"HOME=/home/me <host>",
+		"MODE=release <task build>",
+		"REGION=prod <" + prodFile + " (profile prod)>",
+		"TOKEN=file <" + filepath.Join(dir, ".env") + ">",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("vars = %q, want %q", got, want)
+	}
+
+	got = nil
+	for _, s := range steps {
+		for _, v := range s.Env {
+			got = append(got, s.Step+": "+v.Name+"="+v.Value+" <"+v.Source+">")
+		}
+	}
+	want = []string{
+		`compile: CGO_ENABLED=0 <step "compile" of task build>`,
+		`compile: OUT={{.out}} <step "compile" of task build (unresolved)>`,
+		`lint: LEVEL=lint <step "lint" of task build>`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("steps = %q, want %q", got, want)
+	}
+
+	vars, _, err = e.TaskEnv(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 3 || slices.ContainsFunc(vars, func(v EnvVar) bool { return v.Name == "MODE" }) {
+		t.Errorf("config env = %v", vars)
+	}
+}
@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// FuncDoc describes a template function for `gofer funcs`.
+type FuncDoc struct {
+	Name  string
+	Usage string
+	Desc  string
+}
+
+// FuncDocs documents every function in the map returned by funcMap, in the
+// order `gofer funcs` lists them.
+var FuncDocs = []FuncDoc{
+	{"shellquote", `{{ .msg | shellquote }}`, "Quote a value as a single word for the shell (sh, or cmd on Windows)"},
+	{"raw", `{{ .flags | raw }}`, `Insert a value unquoted even with escape: "auto"`},
+	{"default", `{{ .tag | default "latest" }}`, "The value, or the given default if it is empty"},
+	{"upper", `{{ .name | upper }}`, "Convert to upper case"},
+	{"lower", `{{ .name | lower }}`, "Convert to lower case"},
+	{"replace", `{{ .branch | replace "/" "-" }}`, "Replace every occurrence of a string"},
+	{"trim", `{{ .input | trim }}`, "Remove leading and trailing whitespace"},
+	{"split", `{{ .list | split "," }}`, "Split a string into a list"},
+	{"join", `{{ .list | split "," | join " " }}`, "Join a list into a string"},
+	{"env", `{{ env "HOME" }}`, "A variable from the task's environment, empty if unset"},
+	{"os", `{{ os }}`, "Operating system (linux, darwin, windows, ...)"},
+	{"arch", `{{ arch }}`, "CPU architecture (amd64, arm64, ...)"},
+	{"joinPath", `{{ joinPath "build" .name "out" }}`, "Join path elements with the OS separator"},
+	{"now", `{{ now }}`, "The current time"},
+	{"date", `{{ now | date "2006-01-02" }}`, "Format a time with a Go layout"},
+	{"toJson", `{{ .name | toJson }}`, "Encode a value as JSON"},
+	{"exists", `{{ if exists "go.mod" }}...{{ end }}`, "Whether a file or directory exists (relative to the task's directory)"},
+	{"readFile", `{{ readFile "VERSION" | trim }}`, "Contents of a file (relative to the task's directory)"},
+}
+
+// funcMap returns the template functions for a task running with env in
+// dir (empty meaning the current directory).
+func funcMap(env []string, dir string) template.FuncMap {
+	path := func(p string) string {
+		if dir != "" && !filepath.IsAbs(p) {
+			return filepath.Join(dir, p)
+		}
+		return p
+	}
+
+	return template.FuncMap{
+		"shellquote": shellQuote,
+		"raw":        func(v any) string { return fmt.Sprint(v) },
+		"default": func(def string, value any) any {
+			if value == nil || value == "" {
+				return def
+			}
+			return value
+		},
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"trim":    strings.TrimSpace,
+		"split":   func(sep, s string) []string { return strings.Split(s, sep) },
+		"join": func(sep string, list any) (string, error) {
+			switch l := list.(type) {
+			case []string:
+				return strings.Join(l, sep), nil
+			case []any:
+				parts := make([]string, len(l))
+				for i, v := range l {
+					parts[i] = fmt.Sprint(v)
+				}
+				return strings.Join(parts, sep), nil
+			}
+			return "", fmt.Errorf("join: cannot join %T", list)
+		},
+		"env": func(name string) string {
+			for i := len(env) - 1; i >= 0; i-- {
+				if k, v, ok := strings.Cut(env[i], "="); ok && k == name {
+					return v
+				}
+			}
+			return ""
+		},
+		"os":       func() string { return runtime.GOOS },
+		"arch":     func() string { return runtime.GOARCH },
+		"joinPath": filepath.Join,
+		"now":      time.Now,
+		"date":     func(layout string, t time.Time) string { return t.Format(layout) },
+		"toJson": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"exists": func(p string) bool {
+			_, err := os.Stat(path(p))
+			return err == nil
+		},
+		"readFile": func(p string) (string, error) {
+			b, err := os.ReadFile(path(p))
+			return string(b), err
+		},
+	}
+}
+
+// TemplateFuncs returns the template functions as seen from the current
+// process, for parsing templates outside of a run (e.g. validation).
+func TemplateFuncs() template.FuncMap {
+	return funcMap(os.Environ(), "")
+}
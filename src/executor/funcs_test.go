@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFuncDocsMatchFuncMap(t *testing.T) {
+	funcs := funcMap(nil, "")
+	for _, d := range FuncDocs {
+		if _, ok := funcs[d.Name]; !ok {
+			t.Errorf("FuncDocs lists %q, which is not in funcMap", d.Name)
+		}
+		if !strings.Contains(d.Usage, d.Name) {
+			t.Errorf("usage of %q doesn't mention it: %q", d.Name, d.Usage)
+		}
+	}
+	if len(funcs) != len(FuncDocs) {
+		t.Errorf("funcMap has %d functions, FuncDocs documents %d", len(funcs), len(FuncDocs))
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte("1.2.3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := &frame{
+		params: map[string]string{"branch": "feature/x", "empty": "", "msg": "it's done", "list": "a,b,c"},
+		env:    []string{"HOME=/home/me"},
+		dir:    dir,
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{ .branch | replace "/" "-" | upper }}`, "FEATURE-X"},
+		{`{{ "MiXed" | lower }}`, "mixed"},
+		{`{{ .empty | default "latest" }}`, "latest"},
+		{`{{ .branch | default "latest" }}`, "feature/x"},
+		{`{{ "  x  " | trim }}`, "x"},
+		{`{{ .list | split "," | join " " }}`, "a b c"},
+		{`{{ env "HOME" }}[{{ env "NOPE" }}]`, "/home/me[]"},
+		{`{{ os }}/{{ arch }}`, runtime.GOOS + "/" + runtime.GOARCH},
+		{`{{ joinPath "a" "b" }}`, filepath.Join("a", "b")},
+		{`{{ .msg | toJson }}`, `"it's done"`},
+		{`{{ exists "VERSION" }} {{ exists "missing" }}`, "true false"},
+		{`{{ readFile "VERSION" | trim }}`, "1.2.3"},
+		{`{{ now | date "2006" }}`, time.Now().Format("2006")},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct{ tmpl, want string }{`{{ .msg | shellquote }}`, `'it'\''s done'`})
+	}
+
+	for _, tt := range tests {
+		got, err := f.resolve(tt.tmpl)
+		if err != nil {
+			t.Errorf("%s: %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	if _, err := f.resolve(`{{ readFile "missing" }}`); err == nil {
+		t.Error("readFile of a missing file should fail")
+	}
+}
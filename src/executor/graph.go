@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azmekk/gofer/config"
+)
+
+// checkCycles walks every task reachable from root through deps and ref steps
+// and reports the first cycle it finds. Unknown task names are left for the
+// executor to report when it reaches them.
+func checkCycles(cfg *config.GoferConfig, root string) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(ref string) error
+	visit = func(ref string) error {
+		switch state[ref] {
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == ref {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), ref)
+			return fmt.Errorf("cycle detected: %s", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+
+		task, err := cfg.ResolveTask(ref)
+		if err != nil {
+			return nil
+		}
+
+		state[ref] = visiting
+		path = append(path, ref)
+		for _, next := range taskEdges(*task) {
+			if err := visit(config.Qualify(config.Namespace(ref), next)); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[ref] = visited
+		return nil
+	}
+
+	return visit(root)
+}
+
+// taskEdges returns the names of all tasks a task depends on or refers to,
+// relative to the task's own namespace.
+func taskEdges(task config.Task) []string {
+	edges := append([]string{}, task.Deps...)
+	edges = appendStepRefs(edges, task.Steps)
+	return appendStepRefs(edges, task.Finally)
+}
+
+func appendStepRefs(edges []string, steps []config.Step) []string {
+	for _, s := range steps {
+		if s.Ref != "" {
+			edges = append(edges, s.Ref)
+		}
+		edges = appendStepRefs(edges, s.Concurrent)
+	}
+	return edges
+}
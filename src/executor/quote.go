@@ -0,0 +1,195 @@
+package executor
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// QuoteArgs quotes each arg for the shell commands run on this OS and joins
+// them with spaces.
+func QuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SplitArgs splits s into words the way the shell commands run on this OS
+// would, without expanding anything; it undoes QuoteArgs. Operators such as
+// ";" or "&" are just part of a word.
+func SplitArgs(s string) ([]string, error) {
+	if runtime.GOOS == "windows" {
+		return splitCmd(s)
+	}
+	return splitSh(s)
+}
+
+// shellQuote quotes s as a single word for the shell commands run on this
+// OS: sh, or cmd on Windows.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return quoteCmd(s)
+	}
+	return quoteSh(s)
+}
+
+// quoteSh quotes s as a single word for sh. Words made only of safe
+// characters are left alone.
+func quoteSh(s string) string {
+	if s != "" && strings.Trim(s, shSafe) == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+const shSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-"
+
+// quoteCmd quotes s as a single argument for a program started by cmd.exe,
+// following the rules of CommandLineToArgvW: the argument is wrapped in
+// double quotes, embedded quotes are backslash-escaped and backslashes are
+// doubled only where they precede a quote. cmd expands %VAR% even inside
+// double quotes, so each % is written as ^% between closed quotes, where the
+// caret escapes it. gofer runs cmd without /V:ON, so !VAR! isn't expanded.
+func quoteCmd(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"&|<>^()%!") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		case '%':
+			// Backslashes before the closing quote must not escape it.
+			b.WriteString(strings.Repeat(`\`, slashes))
+			b.WriteString(`"^%"`)
+			slashes = 0
+			continue
+		default:
+			slashes = 0
+		}
+		b.WriteByte(c)
+	}
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// splitSh splits s into words by sh's quoting rules: single quotes keep
+// everything, double quotes keep everything but backslash escapes of $, `,
+// ", \ and newline, and a backslash outside quotes escapes any character.
+func splitSh(s string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ' in %q", s)
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated \" in %q", s)
+			}
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// splitCmd splits s into arguments the way cmd.exe and CommandLineToArgvW
+// would: a caret outside double quotes escapes the next character, and
+// backslashes are only special before a double quote.
+func splitCmd(s string) ([]string, error) {
+	var (
+		words    []string
+		word     strings.Builder
+		inWord   bool
+		inQuotes bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c == ' ' || c == '\t') && !inQuotes:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case c == '^' && !inQuotes:
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+		case c == '\\':
+			n := 1
+			for i+n < len(s) && s[i+n] == '\\' {
+				n++
+			}
+			if i+n < len(s) && s[i+n] == '"' {
+				word.WriteString(strings.Repeat(`\`, n/2))
+				if n%2 == 1 {
+					word.WriteByte('"')
+					i += n
+				} else {
+					i += n - 1
+				}
+			} else {
+				word.WriteString(strings.Repeat(`\`, n))
+				i += n - 1
+			}
+		case c == '"':
+			inQuotes = !inQuotes
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated \" in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
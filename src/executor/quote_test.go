@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestQuoteSh(t *testing.T) {
+	tests := map[string]string{
+		"plain":           "plain",
+		"-run":            "-run",
+		"a/b.go":          "a/b.go",
+		"":                "''",
+		"two words":       "'two words'",
+		"it's":            `'it'\''s'`,
+		"$HOME":           "'$HOME'",
+		"TestFoo|TestBar": "'TestFoo|TestBar'",
+	}
+	for in, want := range tests {
+		if got := quoteSh(in); got != want {
+			t.Errorf("quoteSh(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestQuoteCmd(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "plain",
+		"":           `""`,
+		"two words":  `"two words"`,
+		`say "hi"`:   `"say \"hi\""`,
+		`C:\dir\`:    `C:\dir\`,
+		`C:\my dir\`: `"C:\my dir\\"`,
+		"a&b":        `"a&b"`,
+		"%PATH%":     `""^%"PATH"^%""`,
+		`50% off\`:   `"50"^%" off\\"`,
+	}
+	for in, want := range tests {
+		if got := quoteCmd(in); got != want {
+			t.Errorf("quoteCmd(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	lists := [][]string{
+		{"plain"},
+		{"two words", "it's", `say "hi"`, "$HOME", ""},
+		{`C:\my dir\`, `a\"b`, "a&b", "x;", "%PATH%", `dir\%x`},
+	}
+	for _, args := range lists {
+		quoted := make([]string, len(args))
+		for i, a := range args {
+			quoted[i] = quoteSh(a)
+		}
+		if got, err := splitSh(strings.Join(quoted, " ")); err != nil || !slices.Equal(got, args) {
+			t.Errorf("splitSh(quoteSh(%q)) = %q, %v", args, got, err)
+		}
+		for i, a := range args {
+			quoted[i] = quoteCmd(a)
+		}
+		if got, err := splitCmd(strings.Join(quoted, " ")); err != nil || !slices.Equal(got, args) {
+			t.Errorf("splitCmd(quoteCmd(%q)) = %q, %v", args, got, err)
+		}
+	}
+
+	raw := map[string][]string{
+		"x; echo INJECTED":  {"x;", "echo", "INJECTED"},
+		`a\ b "c d" 'e f'`:  {"a b", "c d", "e f"},
+		`"a \$b \x"`:        {`a $b \x`},
+		"  spaced\tout\n  ": {"spaced", "out"},
+	}
+	for in, want := range raw {
+		if got, err := splitSh(in); err != nil || !slices.Equal(got, want) {
+			t.Errorf("splitSh(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := splitSh("'open"); err == nil {
+		t.Error("splitSh accepted an unterminated quote")
+	}
+	if _, err := splitCmd(`"open`); err == nil {
+		t.Error("splitCmd accepted an unterminated quote")
+	}
+}
@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azmekk/gofer/config"
+	"github.com/Azmekk/gofer/output"
+)
+
+// DefaultRetryDelay is waited before a retry when the step sets no delay.
+const DefaultRetryDelay = time.Second
+
+// TimeoutError is the cancellation cause of a step that ran longer than its
+// timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+// runWithRetry runs a step with its timeout applied to every attempt,
+// retrying failed attempts as configured by step.Retry. Interrupted and
+// cancelled attempts are never retried.
+func (e *Executor) runWithRetry(ctx context.Context, step config.Step, f *frame, index int) error {
+	label := output.StepLabel(step, index)
+
+	var timeout time.Duration
+	if step.Timeout != "" {
+		d, err := time.ParseDuration(step.Timeout)
+		if err != nil {
+			output.PrintStepFail(e.Stderr, label, err)
+			return fmt.Errorf("invalid timeout %q: %w", step.Timeout, err)
+		}
+		timeout = d
+	}
+
+	retries, delay, backoff := 0, DefaultRetryDelay, 1.0
+	if r := step.Retry; r != nil {
+		retries = r.Count
+		if r.Delay != "" {
+			d, err := time.ParseDuration(r.Delay)
+			if err != nil {
+				output.PrintStepFail(e.Stderr, label, err)
+				return fmt.Errorf("invalid retry delay %q: %w", r.Delay, err)
+			}
+			delay = d
+		}
+		if r.Backoff > 0 {
+			backoff = r.Backoff
+		}
+	}
+
+	// Only the final attempt may downgrade a failure to a warning, otherwise
+	// ignore_error would swallow the failure before it could be retried.
+	attemptStep := step
+	attemptStep.IgnoreError = false
+	if retries == 0 {
+		attemptStep = step
+	}
+
+	err := e.runAttempt(ctx, attemptStep, f, index, timeout)
+	for attempt := 1; err != nil && attempt <= retries && ctx.Err() == nil; attempt++ {
+		output.PrintStepRetry(e.Stderr, label, attempt, retries, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return e.stepFailed(ctx, label, context.Cause(ctx))
+		}
+		delay = time.Duration(float64(delay) * backoff)
+		if attempt == retries {
+			attemptStep = step
+		}
+		err = e.runAttempt(ctx, attemptStep, f, index, timeout)
+	}
+	return err
+}
+
+// runAttempt runs the step once, cancelling it with a *TimeoutError cause if
+// it is still running after timeout. A zero timeout means no limit.
+func (e *Executor) runAttempt(ctx context.Context, step config.Step, f *frame, index int, timeout time.Duration) error {
+	if timeout <= 0 {
+		return e.runStep(ctx, step, f, index)
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, &TimeoutError{Timeout: timeout})
+	defer cancel()
+	return e.runStep(ctx, step, f, index)
+}
@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azmekk/gofer/config"
+)
+
+func TestRunTask_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"slow": {Desc: "slow", Steps: []config.Step{{Name: "nap", Cmd: "sleep 10", Timeout: "200ms"}}},
+		},
+	}
+	e, _, stderr := newTestExecutor(cfg, map[string]string{})
+	start := time.Now()
+	err := e.RunTask(context.Background(), "slow")
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("step was not stopped at its timeout")
+	}
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("err = %v, want *TimeoutError", err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("error = %q, want 'timed out after 200ms'", err.Error())
+	}
+	if !strings.Contains(stderr.String(), "✗ nap: timed out after 200ms") {
+		t.Errorf("stderr = %q, want timeout failure line", stderr.String())
+	}
+}
+
+func TestRunTask_RetrySucceeds(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh arithmetic")
+	}
+	t.Chdir(t.TempDir())
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"flaky": {
+				Desc: "flaky",
+				Steps: []config.Step{{
+					Name:  "flaky",
+					Cmd:   "echo x >> attempts; test $(wc -l < attempts) -ge 3",
+					Retry: &config.Retry{Count: 3, Delay: "10ms", Backoff: 2},
+				}},
+			},
+		},
+	}
+	e, _, stderr := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "flaky"); err != nil {
+		t.Fatal(err)
+	}
+	out := stderr.String()
+	for _, want := range []string{"flaky: retry 1/3 in 10ms", "flaky: retry 2/3 in 20ms"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stderr missing %q: %q", want, out)
+		}
+	}
+	if strings.Contains(out, "retry 3/3") {
+		t.Errorf("should stop retrying after success: %q", out)
+	}
+}
+
+func TestRunTask_RetryExhausted(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"broken": {
+				Desc: "broken",
+				Steps: []config.Step{
+					{Cmd: "exit 1", Retry: &config.Retry{Count: 2, Delay: "1ms"}},
+					{Cmd: "echo should-not-run"},
+				},
+			},
+		},
+	}
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "broken"); err == nil {
+		t.Fatal("expected error after retries are exhausted")
+	}
+	if n := strings.Count(stderr.String(), "▸ exit 1"); n != 3 {
+		t.Errorf("step started %d times, want 3: %q", n, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "should-not-run") {
+		t.Error("later steps should not run after a failed step")
+	}
+}
+
+func TestRunTask_RetryTimeoutRef(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"slow": {Desc: "slow", Steps: []config.Step{{Cmd: "sleep 10"}}},
+			"main": {
+				Desc: "main",
+				Steps: []config.Step{{
+					Ref:     "slow",
+					Timeout: "100ms",
+					Retry:   &config.Retry{Count: 1, Delay: "1ms"},
+				}},
+			},
+		},
+	}
+	e, _, stderr := newTestExecutor(cfg, map[string]string{})
+	start := time.Now()
+	err := e.RunTask(context.Background(), "main")
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("err = %v, want *TimeoutError", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("timeout was not applied to the ref step")
+	}
+	if !strings.Contains(stderr.String(), "slow: retry 1/1") {
+		t.Errorf("stderr = %q, want a retry line", stderr.String())
+	}
+}
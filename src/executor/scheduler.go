@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azmekk/gofer/output"
+)
+
+// scheduler is shared by every Executor in a single gofer invocation. It
+// guarantees that each task+params combination reached through deps runs at
+// most once, with concurrent callers waiting on the first run's result, and
+// bounds how many commands run at the same time.
+type scheduler struct {
+	mu    sync.Mutex
+	runs  map[string]*taskRun
+	slots chan struct{}
+
+	promptMu sync.Mutex
+	answers  map[string]string
+
+	varsMu sync.Mutex
+	vars   map[string]*varRun
+
+	// secrets are the values redacted from the run's output, or nil when
+	// the config marks nothing as secret.
+	secrets *output.Secrets
+}
+
+type varRun struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+type taskRun struct {
+	done chan struct{}
+	err  error
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{runs: make(map[string]*taskRun), answers: make(map[string]string), vars: make(map[string]*varRun)}
+}
+
+// prompt returns the answer remembered for key, or calls ask and remembers
+// its answer. Only one ask runs at a time so prompts from concurrent steps
+// don't interleave on the terminal.
+func (s *scheduler) prompt(key string, ask func() (string, error)) (string, error) {
+	s.promptMu.Lock()
+	defer s.promptMu.Unlock()
+	if v, ok := s.answers[key]; ok {
+		return v, nil
+	}
+	v, err := ask()
+	if err != nil {
+		return "", err
+	}
+	s.answers[key] = v
+	return v, nil
+}
+
+// once runs fn for key unless it has already run (or is running), in which
+// case it waits for that run and returns its error.
+func (s *scheduler) once(key string, fn func() error) error {
+	s.mu.Lock()
+	if r, ok := s.runs[key]; ok {
+		s.mu.Unlock()
+		<-r.done
+		return r.err
+	}
+	r := &taskRun{done: make(chan struct{})}
+	s.runs[key] = r
+	s.mu.Unlock()
+
+	r.err = fn()
+	close(r.done)
+	return r.err
+}
+
+// limit bounds the number of commands that may run at once across the whole
+// invocation. n <= 0 means no limit. It must be called before any work starts.
+func (s *scheduler) limit(n int) {
+	if n > 0 && s.slots == nil {
+		s.slots = make(chan struct{}, n)
+	}
+}
+
+// acquire blocks until a command slot is free or ctx is done.
+func (s *scheduler) acquire(ctx context.Context) error {
+	return acquireSlot(ctx, s.slots)
+}
+
+func (s *scheduler) release() {
+	releaseSlot(s.slots)
+}
+
+// acquireSlot takes a slot from a semaphore channel. A nil channel means
+// unlimited and never blocks.
+func acquireSlot(ctx context.Context, slots chan struct{}) error {
+	if slots == nil {
+		return nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+func releaseSlot(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
+// runKey identifies a task invocation by its name and the values of the
+// params it declares. Params the task does not declare cannot change what it
+// does, so they are left out of the key.
+func runKey(f *frame) string {
+	names := make([]string, 0, len(f.task.Params))
+	for _, p := range f.task.Params {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(f.ref)
+	for _, n := range names {
+		b.WriteString("\x00")
+		b.WriteString(n)
+		b.WriteString("=")
+		b.WriteString(f.params[n])
+	}
+	return b.String()
+}
+
+// shellVar returns the output of the var command identified by key, calling
+// run only the first time the key is seen. Concurrent callers wait for the
+// first one.
+func (s *scheduler) shellVar(key string, run func() (string, error)) (string, error) {
+	s.varsMu.Lock()
+	if r, ok := s.vars[key]; ok {
+		s.varsMu.Unlock()
+		<-r.done
+		return r.value, r.err
+	}
+	r := &varRun{done: make(chan struct{})}
+	s.vars[key] = r
+	s.varsMu.Unlock()
+
+	r.value, r.err = run()
+	close(r.done)
+	return r.value, r.err
+}
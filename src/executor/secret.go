@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"strings"
+
+	goferenv "github.com/Azmekk/gofer/env"
+	"github.com/Azmekk/gofer/output"
+)
+
+// redactOutput makes e's writers redact the run's secrets and returns a func
+// that flushes them and puts the original writers back. Frames add the
+// values of secret params and env_secret variables to the set as they are
+// resolved. Only configs that mark something as secret get this, because
+// commands whose output is redacted no longer write straight to the
+// terminal.
+func (e *Executor) redactOutput() func() {
+	stdout, stderr := e.Stdout, e.Stderr
+	e.sched.secrets = &output.Secrets{}
+	redactedOut := output.NewRedactWriter(stdout, e.sched.secrets)
+	redactedErr := output.NewRedactWriter(stderr, e.sched.secrets)
+	e.Stdout, e.Stderr = redactedOut, redactedErr
+	return func() {
+		redactedOut.Flush()
+		redactedErr.Flush()
+		e.Stdout, e.Stderr = stdout, stderr
+	}
+}
+
+// addSecretParams adds the values of the task's secret params to the run's
+// secrets.
+func (f *frame) addSecretParams() {
+	for _, p := range f.task.Params {
+		if p.Secret {
+			f.secrets.Add(f.params[p.Name])
+		}
+	}
+}
+
+// addSecretEnv adds the values of the variables of env, a list of KEY=VALUE
+// entries, that env_secret marks as secret to the run's secrets.
+func (f *frame) addSecretEnv(env []string) {
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		if f.isSecretEnv(key) {
+			f.secrets.Add(value)
+		}
+	}
+}
+
+// isSecretEnv reports whether env_secret marks the variable key as secret.
+func (f *frame) isSecretEnv(key string) bool {
+	return goferenv.Matches(key, f.secretEnv)
+}
+
+// redactError returns err with the secrets in its message replaced. The
+// result still unwraps to err, so errors.Is and errors.As see through it.
+func redactError(secrets *output.Secrets, err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := secrets.Redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
+
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
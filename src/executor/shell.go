@@ -1,13 +1,41 @@
 package executor
 
 import (
+	"context"
 	"os/exec"
 	"runtime"
+	"time"
 )
 
+// DefaultGracePeriod is how long a cancelled command gets to exit before it
+// is killed.
+const DefaultGracePeriod = 5 * time.Second
+
 func ShellCommand(command string) *exec.Cmd {
 	if runtime.GOOS == "windows" {
 		return exec.Command("cmd", "/C", command)
 	}
 	return exec.Command("sh", "-c", command)
 }
+
+// ShellCommandContext is like ShellCommand, but the shell runs in its own
+// process group. When ctx is done the whole group is sent the signal that
+// caused the cancellation (SIGTERM if none) and, if it is still running after
+// grace, killed. Commands that may read from the terminal are run with
+// runCommand.
+func ShellCommandContext(ctx context.Context, command string, grace time.Duration) *exec.Cmd {
+	name, args := "sh", []string{"-c", command}
+	if runtime.GOOS == "windows" {
+		name, args = "cmd", []string{"/C", command}
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminate(cmd, cancelSignal(ctx), grace)
+	}
+	// Don't wait forever on output pipes held open by grandchildren that
+	// survived the group kill.
+	cmd.WaitDelay = grace + time.Second
+	return cmd
+}
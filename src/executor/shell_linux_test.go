@@ -0,0 +1,172 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azmekk/gofer/config"
+	"golang.org/x/sys/unix"
+)
+
+// openPty opens a new pseudo-terminal and returns its master and slave ends.
+func openPty(t *testing.T) (master, slave *os.File) {
+	t.Helper()
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	if err := unix.IoctlSetPointerInt(int(m.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		m.Close()
+		t.Skipf("unlockpt: %v", err)
+	}
+	n, err := unix.IoctlGetInt(int(m.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		m.Close()
+		t.Skipf("ptsname: %v", err)
+	}
+	s, err := os.OpenFile("/dev/pts/"+strconv.Itoa(n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		m.Close()
+		t.Skipf("open pts: %v", err)
+	}
+	return m, s
+}
+
+// TestRunTask_PtyHelper runs the task GOFER_PTY_HELPER names. runInPty
+// starts it in a new session whose controlling terminal is a pty, as gofer
+// is from a shell.
+func TestRunTask_PtyHelper(t *testing.T) {
+	var tasks map[string]config.Task
+	switch os.Getenv("GOFER_PTY_HELPER") {
+	case "":
+		t.Skip("helper for the pty tests")
+	case "interactive":
+		tasks = map[string]config.Task{
+			"t": {Desc: "ask", Steps: []config.Step{{Cmd: "read x; echo got=$x"}}},
+		}
+	case "ctrl-c":
+		tasks = map[string]config.Task{
+			"t": {Desc: "wait", Steps: []config.Step{{Cmd: "echo ready; sleep 30"}, {Cmd: "echo after"}}},
+		}
+	case "timeout":
+		tasks = map[string]config.Task{
+			"t": {Desc: "slow", Steps: []config.Step{{Cmd: "sleep 31 & echo $! > sleep.pid; wait $!; echo after", Timeout: "500ms"}}},
+		}
+	case "fail-fast":
+		tasks = map[string]config.Task{
+			"t": {Desc: "parallel", Steps: []config.Step{{FailFast: true, Concurrent: []config.Step{
+				{Name: "slow", Cmd: "sleep 32 & echo $! > sleep.pid; wait $!"},
+				{Name: "bad", Cmd: "sleep 0.5; exit 3"},
+			}}}},
+		}
+	}
+	t.Chdir(t.TempDir())
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+	e := New(&config.GoferConfig{Tasks: tasks}, nil, map[string]string{})
+	err := e.RunTask(ctx, "t")
+	fmt.Printf("result: %v\n", err)
+
+	if data, err := os.ReadFile("sleep.pid"); err == nil {
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		defer syscall.Kill(pid, syscall.SIGKILL)
+		for deadline := time.Now().Add(2 * time.Second); running(pid); time.Sleep(50 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				fmt.Println("grandchild survived")
+				break
+			}
+		}
+	}
+}
+
+// running reports whether process pid exists and isn't a zombie.
+func running(pid int) bool {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	_, rest, _ := strings.Cut(string(data), ") ")
+	return !strings.HasPrefix(rest, "Z")
+}
+
+// runInPty runs TestRunTask_PtyHelper as helper in a pty, calls interact
+// with the pty for each line of output until it returns true, and returns
+// all of the output.
+func runInPty(t *testing.T, helper string, interact func(master *os.File, line string) bool) string {
+	t.Helper()
+	master, slave := openPty(t)
+	defer master.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunTask_PtyHelper$")
+	cmd.Env = append(os.Environ(), "GOFER_PTY_HELPER="+helper)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	slave.Close()
+	timer := time.AfterFunc(10*time.Second, func() { cmd.Process.Kill() })
+	defer timer.Stop()
+
+	var b strings.Builder
+	r := bufio.NewReader(master)
+	for {
+		line, err := r.ReadString('\n')
+		b.WriteString(line)
+		if err != nil || interact(master, line) {
+			break
+		}
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+	return b.String()
+}
+
+func TestRunTask_Interactive(t *testing.T) {
+	out := runInPty(t, "interactive", func(master *os.File, line string) bool {
+		if strings.Contains(line, "▸ read x") {
+			master.Write([]byte("hello\n"))
+		}
+		return strings.Contains(line, "result:")
+	})
+	if !strings.Contains(out, "got=hello") || !strings.Contains(out, "result: <nil>") {
+		t.Errorf("interactive step didn't read from the terminal:\n%s", out)
+	}
+}
+
+func TestRunTask_CtrlCInTerminal(t *testing.T) {
+	out := runInPty(t, "ctrl-c", func(master *os.File, line string) bool {
+		if strings.HasSuffix(strings.TrimRight(line, "\r\n"), "ready") {
+			master.Write([]byte{3})
+		}
+		return strings.Contains(line, "result:")
+	})
+	if !strings.Contains(out, "result: interrupted") || strings.Contains(out, "after") {
+		t.Errorf("Ctrl-C sent to the command didn't interrupt the run:\n%s", out)
+	}
+}
+
+func TestRunTask_CancelInTerminal(t *testing.T) {
+	for _, tt := range []struct{ helper, want string }{
+		{"timeout", "timed out"},
+		{"fail-fast", "slow: cancelled"},
+	} {
+		out := runInPty(t, tt.helper, func(master *os.File, line string) bool {
+			return strings.HasPrefix(line, "PASS") || strings.HasPrefix(line, "FAIL")
+		})
+		if !strings.Contains(out, "result: ") || !strings.Contains(out, tt.want) {
+			t.Errorf("%s: unexpected output:\n%s", tt.helper, out)
+		}
+		if strings.Contains(out, "grandchild survived") {
+			t.Errorf("%s: a command's child survived cancellation:\n%s", tt.helper, out)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azmekk/gofer/config"
+)
+
+func runInterrupted(t *testing.T, cmd string, grace time.Duration) (string, time.Duration, error) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("signal forwarding is unix-only")
+	}
+
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"slow": {Desc: "slow", Steps: []config.Step{{Name: "sleeper", Cmd: cmd}}},
+		},
+	}
+	e, _, stderr := newTestExecutor(cfg, map[string]string{})
+	e.GracePeriod = grace
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(200*time.Millisecond, func() {
+		cancel(&InterruptError{Signal: syscall.SIGINT})
+	})
+
+	start := time.Now()
+	err := e.RunTask(ctx, "slow")
+	return stderr.String(), time.Since(start), err
+}
+
+func TestRunTask_Interrupted(t *testing.T) {
+	stderr, elapsed, err := runInterrupted(t, "sleep 10", 5*time.Second)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want ErrInterrupted", err)
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("command was not stopped promptly (took %s)", elapsed)
+	}
+	if !strings.Contains(stderr, "sleeper: interrupted") {
+		t.Errorf("stderr = %q, want interrupted status line", stderr)
+	}
+	if strings.Contains(stderr, "✗") {
+		t.Errorf("interrupted step should not be reported as failed: %q", stderr)
+	}
+}
+
+func TestRunTask_InterruptEscalatesToKill(t *testing.T) {
+	_, elapsed, err := runInterrupted(t, "trap '' INT; sleep 10", 300*time.Millisecond)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want ErrInterrupted", err)
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("command ignoring SIGINT was not killed after the grace period (took %s)", elapsed)
+	}
+}
+
+func TestRunTask_InterruptStopsLaterSteps(t *testing.T) {
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"two": {Desc: "two", Steps: []config.Step{{Cmd: "echo first"}, {Cmd: "echo second"}}},
+		},
+	}
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(&InterruptError{Signal: syscall.SIGINT})
+
+	if err := e.RunTask(ctx, "two"); !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want ErrInterrupted", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("no step should start after an interrupt, got %q", stdout.String())
+	}
+}
+
+func TestRunTask_FinallyAfterInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signal forwarding is unix-only")
+	}
+
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"serve": {
+				Desc:    "serve",
+				Steps:   []config.Step{{Name: "server", Cmd: "sleep 10"}},
+				Finally: []config.Step{{Name: "teardown", Cmd: "echo torn-down"}},
+			},
+		},
+	}
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{})
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(200*time.Millisecond, func() {
+		cancel(&InterruptError{Signal: syscall.SIGINT})
+	})
+
+	err := e.RunTask(ctx, "serve")
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want ErrInterrupted", err)
+	}
+	if !strings.Contains(stdout.String(), "torn-down") {
+		t.Errorf("finally step did not run after interrupt: stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "✓ teardown") {
+		t.Errorf("stderr = %q, want teardown reported as done", stderr.String())
+	}
+}
+
+func TestRunTask_SecondInterruptStopsFinally(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signal forwarding is unix-only")
+	}
+
+	cfg := &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"serve": {
+				Desc:    "serve",
+				Steps:   []config.Step{{Name: "server", Cmd: "sleep 10"}},
+				Finally: []config.Step{{Name: "teardown", Cmd: "sleep 10"}},
+			},
+		},
+	}
+	e, _, stderr := newTestExecutor(cfg, map[string]string{})
+
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(200*time.Millisecond, func() { self.Signal(os.Interrupt) })
+	time.AfterFunc(600*time.Millisecond, func() { self.Signal(os.Interrupt) })
+
+	start := time.Now()
+	err = e.RunTask(ctx, "serve")
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("err = %v, want ErrInterrupted", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("second interrupt did not stop the finally step (took %s)", elapsed)
+	}
+	if !strings.Contains(stderr.String(), "teardown: interrupted") {
+		t.Errorf("stderr = %q, want teardown reported as interrupted", stderr.String())
+	}
+}
@@ -0,0 +1,96 @@
+//go:build !windows
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminate forwards sig to the command's process group and escalates to
+// SIGKILL once grace has passed.
+func terminate(cmd *exec.Cmd, sig os.Signal, grace time.Duration) error {
+	pgid := -cmd.Process.Pid
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		s = syscall.SIGTERM
+	}
+	if err := syscall.Kill(pgid, s); err != nil {
+		return os.ErrProcessDone
+	}
+	time.AfterFunc(grace, func() {
+		syscall.Kill(pgid, syscall.SIGKILL)
+	})
+	return nil
+}
+
+// terminalMu is held by the command that has gofer's terminal, see
+// runCommand.
+var terminalMu sync.Mutex
+
+// runCommand runs cmd, made by ShellCommandContext. Its process group is in
+// the background, and a background group that reads from the terminal is
+// stopped with SIGTTIN, so when cmd reads gofer's stdin and that is the
+// terminal gofer is in the foreground of, cmd's group is made the
+// foreground group while it runs. One command at a time gets the terminal;
+// concurrent ones stay in the background.
+//
+// The terminal then sends Ctrl-C to cmd rather than to gofer. A command
+// killed by SIGINT is reported to the context's NotifyContext as if gofer
+// had received the signal, so the rest of the run stops as usual.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	fd, ok := foregroundTerminal(cmd)
+	if !ok || !terminalMu.TryLock() {
+		return cmd.Run()
+	}
+	defer terminalMu.Unlock()
+
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = fd
+	err := cmd.Run()
+	takeTerminal(fd)
+
+	// A SIGINT gofer forwarded itself has been handled already.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() && ws.Signal() == syscall.SIGINT {
+			interrupt(ctx, syscall.SIGINT)
+		}
+	}
+	return err
+}
+
+// foregroundTerminal returns the descriptor of cmd's stdin if it is gofer's
+// stdin and a terminal whose foreground process group is gofer's.
+func foregroundTerminal(cmd *exec.Cmd) (int, bool) {
+	if cmd.Stdin != os.Stdin {
+		return 0, false
+	}
+	fd := int(os.Stdin.Fd())
+	pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	if err != nil || pgrp != syscall.Getpgrp() {
+		return 0, false
+	}
+	return fd, true
+}
+
+// takeTerminal makes gofer's process group the foreground group of the
+// terminal fd again. gofer is in the background at that point, so SIGTTOU is
+// ignored for the call.
+func takeTerminal(fd int) {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, syscall.Getpgrp())
+}
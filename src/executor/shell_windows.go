@@ -0,0 +1,34 @@
+//go:build windows
+
+package executor
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminate kills the command's whole process tree. Windows has no portable
+// way to deliver SIGINT/SIGTERM to a console process group, so sig and grace
+// are not used.
+func terminate(cmd *exec.Cmd, sig os.Signal, grace time.Duration) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// runCommand runs cmd, made by ShellCommandContext. Windows console
+// processes share the console whatever their process group, so there is no
+// terminal to hand over.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	return cmd.Run()
+}
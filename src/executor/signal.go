@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ErrInterrupted matches (via errors.Is) the error returned when a run is
+// stopped by SIGINT or SIGTERM.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrCancelled is the cancellation cause for steps stopped because a sibling
+// in a fail-fast concurrent block failed.
+var ErrCancelled = errors.New("cancelled")
+
+// InterruptError is the cancellation cause of a context returned by
+// NotifyContext. It records which signal was received so it can be forwarded
+// to running commands.
+type InterruptError struct {
+	Signal os.Signal
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("interrupted (%s)", e.Signal)
+}
+
+func (e *InterruptError) Is(target error) bool {
+	return target == ErrInterrupted
+}
+
+// NotifyContext returns a context that is cancelled with an *InterruptError
+// cause when the process receives SIGINT or SIGTERM. A second signal also
+// stops the finally steps that run after the first one (see cleanupContext);
+// later signals are swallowed so gofer stays alive to clean up its children.
+// Call stop to release the signal handler.
+func NotifyContext(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	abort, cancelAbort := context.WithCancelCause(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	var mu sync.Mutex
+	interrupted := false
+	handle := func(sig os.Signal) {
+		mu.Lock()
+		defer mu.Unlock()
+		if interrupted {
+			cancelAbort(&InterruptError{Signal: sig})
+		}
+		interrupted = true
+		cancel(&InterruptError{Signal: sig})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				handle(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ctx = context.WithValue(ctx, abortKey{}, abort)
+	ctx = context.WithValue(ctx, interruptKey{}, handle)
+	return ctx, func() {
+		signal.Stop(ch)
+		close(done)
+		cancel(context.Canceled)
+		cancelAbort(context.Canceled)
+	}
+}
+
+// interruptKey is the context key of NotifyContext's signal handler, see
+// interrupt.
+type interruptKey struct{}
+
+// interrupt handles sig like the NotifyContext ctx derives from would if the
+// process had received it, cancelling ctx before it returns. It is used for
+// a Ctrl-C that the terminal sent to a command instead of to gofer. Without
+// a NotifyContext it does nothing.
+func interrupt(ctx context.Context, sig os.Signal) {
+	if handle, ok := ctx.Value(interruptKey{}).(func(os.Signal)); ok {
+		handle(sig)
+	}
+}
+
+// abortKey is the context key of the context NotifyContext cancels on a
+// second signal.
+type abortKey struct{}
+
+// cleanupContext returns the context finally steps run with: it keeps ctx's
+// values but not its cancellation, except for the second signal
+// NotifyContext reacts to, so a hung cleanup can still be stopped. Call
+// release once the steps are done.
+func cleanupContext(ctx context.Context) (cleanup context.Context, release func()) {
+	cleanup = context.WithoutCancel(ctx)
+	abort, ok := ctx.Value(abortKey{}).(context.Context)
+	if !ok {
+		return cleanup, func() {}
+	}
+	cleanup, cancel := context.WithCancelCause(cleanup)
+	stop := context.AfterFunc(abort, func() { cancel(context.Cause(abort)) })
+	return cleanup, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// cancelSignal is the signal to forward to commands when ctx is cancelled:
+// the one gofer received, or SIGTERM for any other cancellation.
+func cancelSignal(ctx context.Context) os.Signal {
+	var ie *InterruptError
+	if errors.As(context.Cause(ctx), &ie) {
+		return ie.Signal
+	}
+	return syscall.SIGTERM
+}
@@ -3,19 +3,87 @@ package executor
 import (
 	"bytes"
 	"fmt"
+	"runtime"
+	"strings"
 	"text/template"
 )
 
-func ResolveTemplate(cmdStr string, params map[string]string) (string, error) {
-	tmpl, err := template.New("cmd").Option("missingkey=error").Parse(cmdStr)
+// ResolveTemplate executes cmdStr as a text/template against data, usually
+// a params map, with the functions from TemplateFuncs. Referencing a missing
+// key is an error.
+func ResolveTemplate(cmdStr string, data any) (string, error) {
+	return resolveTemplate(cmdStr, data, TemplateFuncs(), nil, nil)
+}
+
+// resolve executes src as a template in the context of task frame f: with
+// templateData(f) as data and functions bound to the frame's env and dir.
+func (f *frame) resolve(src string) (string, error) {
+	return resolveTemplate(src, templateData(f), funcMap(f.env, f.dir), nil, nil)
+}
+
+// resolveShell is resolve for templates that become shell commands: with
+// escape: "auto" in the task's config, interpolated values are quoted except
+// for ARGS and variadic params, which resolveParams always stores quoted.
+func (f *frame) resolveShell(src string) (string, error) {
+	if !f.escape {
+		return f.resolve(src)
+	}
+	quoted := []string{"ARGS"}
+	for _, p := range f.task.Params {
+		if p.Variadic {
+			quoted = append(quoted, p.Name)
+		}
+	}
+	return resolveTemplate(src, templateData(f), funcMap(f.env, f.dir), shellQuote, quoted)
+}
+
+// resolveTemplate executes cmdStr against data. A non-nil quote is applied
+// to the output of every action but those escapeActions leaves alone.
+func resolveTemplate(cmdStr string, data any, funcs template.FuncMap, quote func(string) string, quoted []string) (string, error) {
+	tmpl, err := template.New("cmd").Option("missingkey=error").Funcs(funcs).Parse(cmdStr)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template %q: %w", cmdStr, err)
 	}
+	if quote != nil {
+		escapeActions(tmpl, quote, quoted)
+	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, params); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("failed to resolve template %q: %w", cmdStr, err)
 	}
 
 	return buf.String(), nil
 }
+
+// templateData is what templates in a task see: its params, resolved vars
+// and captured values plus the built-ins OS, ARCH, ENV (the task's
+// environment as a map) and ARGS (the quoted passthrough arguments).
+// Built-ins are uppercase so they don't clash with typical param names. On a
+// name clash a param wins over a capture, a capture over a var and a var
+// over a built-in.
+func templateData(f *frame) map[string]any {
+	env := make(map[string]string, len(f.env))
+	for _, kv := range f.env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	data := map[string]any{
+		"OS":   runtime.GOOS,
+		"ARCH": runtime.GOARCH,
+		"ENV":  env,
+		"ARGS": QuoteArgs(f.args),
+	}
+	for k, v := range f.vars {
+		data[k] = v
+	}
+	for k, v := range f.captures.snapshot() {
+		data[k] = v
+	}
+	for k, v := range f.params {
+		data[k] = v
+	}
+	return data
+}
@@ -1,7 +1,11 @@
 package executor
 
 import (
+	"fmt"
+	"runtime"
 	"testing"
+
+	"github.com/Azmekk/gofer/config"
 )
 
 func TestResolveTemplate(t *testing.T) {
@@ -68,3 +72,78 @@ func TestResolveTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateData(t *testing.T) {
+	f := &frame{
+		params: map[string]string{"name": "api", "OS": "plan9"},
+		env:    []string{"HOME=/home/me", "EMPTY=", "HOME=/override"},
+	}
+	got, err := ResolveTemplate("{{.name}} {{.ARCH}} {{.OS}} {{.ENV.HOME}}[{{.ENV.EMPTY}}]", templateData(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "api " + runtime.GOARCH + " plan9 /override[]"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestResolveShell_Escape(t *testing.T) {
+	f := &frame{
+		task: &config.Task{Params: []config.Param{
+			{Name: "msg"},
+			{Name: "files", Variadic: true},
+		}},
+		params: map[string]string{"msg": "it's done", "flags": "-v --all", "files": QuoteArgs([]string{"a b", "c"})},
+		args:   []string{"x y"},
+	}
+	q := shellQuote
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`git commit -m {{.msg}}`, "git commit -m " + q("it's done")},
+		{`ls {{ .flags | raw }}`, "ls -v --all"},
+		{`ls {{ raw .flags }}`, "ls -v --all"},
+		{`echo {{ .msg | shellquote }}`, "echo " + q("it's done")},
+		{`echo {{ .msg | upper }}`, "echo " + q("IT'S DONE")},
+		{`cat {{.files}} {{.ARGS}}`, "cat " + QuoteArgs([]string{"a b", "c"}) + " " + QuoteArgs([]string{"x y"})},
+		{`{{ if .msg }}echo {{.msg}}{{ end }}`, "echo " + q("it's done")},
+		{`{{ $m := .msg }}echo {{ $m }}`, "echo " + q("it's done")},
+		{`echo {{ "" }}`, "echo " + q("")},
+		{`echo {{ len .ARGS }}`, "echo " + q(fmt.Sprint(len(QuoteArgs([]string{"x y"}))))},
+		{`echo {{ exists "no-such-file" }}`, "echo " + q("false")},
+		{`echo {{ 1.5 }}`, "echo " + q("1.5")},
+	}
+	for _, tt := range tests {
+		f.escape = true
+		got, err := f.resolveShell(tt.tmpl)
+		if err != nil {
+			t.Errorf("%s: %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	f.escape = false
+	got, err := f.resolveShell(`git commit -m {{.msg}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "git commit -m it's done" {
+		t.Errorf("without escape got %q", got)
+	}
+}
+
+func TestResolveTemplate_Quote(t *testing.T) {
+	got, err := resolveTemplate("echo {{.path}} {{ .path | raw }}", map[string]string{"path": "my dir"}, TemplateFuncs(), shellQuote, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "echo " + shellQuote("my dir") + " my dir"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
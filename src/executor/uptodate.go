@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azmekk/gofer/config"
+	"github.com/Azmekk/gofer/fingerprint"
+	"github.com/Azmekk/gofer/output"
+)
+
+// runIfOutdated runs a task's steps only when the fingerprint of its sources
+// and generates differs from the one saved after its last successful run.
+//
+// Patterns are relative to the directory the task runs in.
+func (e *Executor) runIfOutdated(ctx context.Context, f *frame) error {
+	ref, task := f.ref, f.task
+	sources, err := resolvePatterns(task.Sources, f)
+	if err != nil {
+		return fmt.Errorf("task %q: sources: %w", ref, err)
+	}
+	generates, err := resolvePatterns(task.Generates, f)
+	if err != nil {
+		return fmt.Errorf("task %q: generates: %w", ref, err)
+	}
+
+	def, err := runInputs(e, f)
+	if err != nil {
+		return err
+	}
+	key := runKey(f)
+	store := fingerprint.NewStore(e.StateDir)
+
+	if !e.Force {
+		sum, ok, err := fingerprint.Sum(sources, generates, string(def))
+		if err != nil {
+			return fmt.Errorf("task %q: %w", ref, err)
+		}
+		if ok && store.UpToDate(key, sum) {
+			output.PrintStepSkip(e.Stderr, ref, "up to date")
+			return nil
+		}
+	}
+
+	if err := e.runSteps(ctx, f); err != nil {
+		return err
+	}
+
+	sum, _, err := fingerprint.Sum(sources, generates, string(def))
+	if err != nil {
+		return fmt.Errorf("task %q: %w", ref, err)
+	}
+	return store.Save(key, sum)
+}
+
+// runInputs returns what, besides its files, decides what a task run does:
+// the task definition, so that editing a command invalidates the fingerprint
+// just like editing a source file does, plus the passthrough args, resolved
+// vars, active profile and every env value that doesn't come from the host
+// (env files, inherited and task env) its commands are resolved with.
+func runInputs(e *Executor, f *frame) ([]byte, error) {
+	env := make(map[string]string)
+	for _, entry := range f.env {
+		key, value, _ := strings.Cut(entry, "=")
+		if f.envSrc[key] != "host" {
+			env[key] = value
+		}
+	}
+	return json.Marshal(struct {
+		Task    *config.Task      `json:"task"`
+		Args    []string          `json:"args"`
+		Vars    map[string]string `json:"vars"`
+		Env     map[string]string `json:"env"`
+		Profile string            `json:"profile"`
+	}{f.task, f.args, f.vars, env, e.Config.Profile})
+}
+
+func resolvePatterns(patterns []string, f *frame) ([]string, error) {
+	resolved := make([]string, len(patterns))
+	for i, p := range patterns {
+		r, err := f.resolve(p)
+		if err != nil {
+			return nil, err
+		}
+		if f.dir != "" && !filepath.IsAbs(r) {
+			r = filepath.Join(f.dir, r)
+		}
+		resolved[i] = r
+	}
+	return resolved, nil
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Azmekk/gofer/config"
+)
+
+func fingerprintConfig() *config.GoferConfig {
+	return &config.GoferConfig{
+		Tasks: map[string]config.Task{
+			"build": {
+				Desc:      "build",
+				Sources:   []string{"in.txt"},
+				Generates: []string{"out.txt"},
+				Steps:     []config.Step{{Cmd: "echo build-ran && echo built > out.txt"}},
+			},
+		},
+	}
+}
+
+func TestRunTask_SkipsWhenUpToDate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("in.txt", []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := fingerprintConfig()
+
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "build-ran") {
+		t.Fatalf("first run should execute, stdout = %q", stdout.String())
+	}
+
+	e, stdout, stderr := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stdout.String(), "build-ran") {
+		t.Error("second run should be skipped")
+	}
+	if !strings.Contains(stderr.String(), "up to date") {
+		t.Errorf("stderr = %q, want 'up to date'", stderr.String())
+	}
+
+	if err := os.WriteFile("in.txt", []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e, stdout, _ = newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "build-ran") {
+		t.Error("changed source should trigger a run")
+	}
+}
+
+func TestRunTask_MissingGeneratesRuns(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("in.txt", []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := fingerprintConfig()
+
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove("out.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "build-ran") {
+		t.Error("missing output should trigger a run")
+	}
+}
+
+func TestRunTask_Force(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("in.txt", []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := fingerprintConfig()
+
+	e, _, _ := newTestExecutor(cfg, map[string]string{})
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+
+	e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+	e.Force = true
+	if err := e.RunTask(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "build-ran") {
+		t.Error("--force should run an up-to-date task")
+	}
+}
+
+func TestRunTask_RerunsWhenInputsChange(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("in.txt", []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := fingerprintConfig()
+	cfg.Vars = map[string]config.Var{"sha": {Sh: "cat sha.txt"}}
+	cfg.EnvFile = config.EnvFiles{config.DefaultEnvFile}
+	task := cfg.Tasks["build"]
+	task.Steps = []config.Step{{Cmd: "echo build-ran {{.sha}} {{.ARGS}} && echo built > out.txt"}}
+	cfg.Tasks["build"] = task
+
+	run := func(args ...string) bool {
+		t.Helper()
+		e, stdout, _ := newTestExecutor(cfg, map[string]string{})
+		e.Args = args
+		if err := e.RunTask(context.Background(), "build"); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Contains(stdout.String(), "build-ran")
+	}
+
+	if err := os.WriteFile("sha.txt", []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("one")
+	if run("one") {
+		t.Error("unchanged inputs should be up to date")
+	}
+	if !run("two") {
+		t.Error("changed args should trigger a run")
+	}
+	if err := os.WriteFile("sha.txt", []byte("def"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !run("two") {
+		t.Error("changed var should trigger a run")
+	}
+
+	// The config's env file isn't set through the task, but counts too.
+	if err := os.WriteFile(".env.gofer", []byte("API=one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("two")
+	if run("two") {
+		t.Error("unchanged env file should be up to date")
+	}
+	if err := os.WriteFile(".env.gofer", []byte("API=two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !run("two") {
+		t.Error("changed env file should trigger a run")
+	}
+}
@@ -0,0 +1,221 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Azmekk/gofer/config"
+)
+
+// resolveVars evaluates the vars the task's templates refer to, and the vars
+// those refer to in turn, storing them in f.vars. Vars nothing refers to are
+// never evaluated. Params shadow vars of the same name.
+func (e *Executor) resolveVars(ctx context.Context, f *frame) error {
+	if len(f.varDefs) == 0 {
+		return nil
+	}
+	refs, err := taskRefs(f.task)
+	if err != nil {
+		return fmt.Errorf("task %q: %w", f.ref, err)
+	}
+
+	r := &varResolver{e: e, f: f, resolving: make(map[string]bool)}
+	f.vars = make(map[string]string)
+	for _, name := range refs {
+		if err := r.resolve(ctx, name); err != nil {
+			return fmt.Errorf("task %q: %w", f.ref, err)
+		}
+	}
+	return nil
+}
+
+type varResolver struct {
+	e         *Executor
+	f         *frame
+	resolving map[string]bool
+}
+
+// resolve evaluates var name into r.f.vars unless it is already there, isn't
+// a var, or is shadowed by a param.
+func (r *varResolver) resolve(ctx context.Context, name string) error {
+	f := r.f
+	def, ok := f.varDefs[name]
+	if !ok {
+		return nil
+	}
+	if _, ok := f.vars[name]; ok {
+		return nil
+	}
+	if _, ok := f.params[name]; ok {
+		return nil
+	}
+	if r.resolving[name] {
+		return fmt.Errorf("var %q refers to itself", name)
+	}
+	r.resolving[name] = true
+	defer delete(r.resolving, name)
+
+	src := def.Value
+	if def.Sh != "" {
+		src = def.Sh
+	}
+	refs, err := templateRefs(src)
+	if err != nil {
+		return fmt.Errorf("var %q: %w", name, err)
+	}
+	for _, ref := range refs {
+		if err := r.resolve(ctx, ref); err != nil {
+			return fmt.Errorf("var %q: %w", name, err)
+		}
+	}
+
+	resolve := f.resolve
+	if def.Sh != "" {
+		resolve = f.resolveShell
+	}
+	value, err := resolve(src)
+	if err != nil {
+		return fmt.Errorf("var %q: %w", name, err)
+	}
+	if def.Sh != "" {
+		value, err = r.e.sched.shellVar(shellVarKey(value, f), func() (string, error) {
+			return r.e.runShellVar(ctx, value, f)
+		})
+		if err != nil {
+			return fmt.Errorf("var %q: %s: %w", name, value, err)
+		}
+	}
+	f.vars[name] = value
+	return nil
+}
+
+// runShellVar runs command in the task's environment and directory and
+// returns its stdout with surrounding whitespace removed. Its stderr is shown.
+func (e *Executor) runShellVar(ctx context.Context, command string, f *frame) (string, error) {
+	cmd := ShellCommandContext(ctx, command, e.GracePeriod)
+	cmd.Env = f.env
+	cmd.Dir = f.dir
+	cmd.Stderr = e.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// shellVarKey identifies a var command for caching: the same command in the
+// same directory and environment gives the same value for the whole run.
+func shellVarKey(command string, f *frame) string {
+	return f.dir + "\x00" + command + "\x00" + strings.Join(f.env, "\x00")
+}
+
+// taskRefs returns the names referenced as {{.name}} anywhere in the task's
+// templates, sorted.
+func taskRefs(task *config.Task) ([]string, error) {
+	names := make(map[string]bool)
+	add := func(src string) error {
+		refs, err := templateRefs(src)
+		for _, r := range refs {
+			names[r] = true
+		}
+		return err
+	}
+
+	srcs := slices.Concat(task.Sources, task.Generates, []string{task.Dir}, slices.Collect(maps.Values(task.Env)))
+	for _, p := range srcs {
+		if err := add(p); err != nil {
+			return nil, err
+		}
+	}
+	if err := addStepRefs(task.Steps, add); err != nil {
+		return nil, err
+	}
+	if err := addStepRefs(task.Finally, add); err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(names)), nil
+}
+
+func addStepRefs(steps []config.Step, add func(string) error) error {
+	for _, s := range steps {
+		srcs := slices.Concat([]string{s.Cmd, s.If, s.IfCmd, s.Dir}, slices.Collect(maps.Values(s.With)), slices.Collect(maps.Values(s.Env)))
+		for _, src := range srcs {
+			if err := add(src); err != nil {
+				return err
+			}
+		}
+		if err := addStepRefs(s.Concurrent, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateRefs returns the top-level names ({{.name}}, {{$.name}}) a
+// template refers to. Names inside with and range blocks are included even
+// though dot may not be the top level there; callers only use the result to
+// decide what to evaluate, so an extra name is harmless.
+func templateRefs(src string) ([]string, error) {
+	if !strings.Contains(src, "{{") {
+		return nil, nil
+	}
+	tmpl, err := template.New("refs").Funcs(funcMap(nil, "")).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %q: %w", src, err)
+	}
+	var refs []string
+	walkRefs(tmpl.Tree.Root, func(name string) { refs = append(refs, name) })
+	return refs, nil
+}
+
+func walkRefs(node parse.Node, add func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkRefs(c, add)
+		}
+	case *parse.ActionNode:
+		walkRefs(n.Pipe, add)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkRefs(c, add)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walkRefs(a, add)
+		}
+	case *parse.FieldNode:
+		add(n.Ident[0])
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			add(n.Ident[1])
+		}
+	case *parse.ChainNode:
+		walkRefs(n.Node, add)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, add)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, add)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, add)
+	case *parse.TemplateNode:
+		walkRefs(n.Pipe, add)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, add func(string)) {
+	walkRefs(n.Pipe, add)
+	walkRefs(n.List, add)
+	walkRefs(n.ElseList, add)
+}
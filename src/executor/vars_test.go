@@ -0,0 +1,24 @@
+package executor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTemplateRefs(t *testing.T) {
+	got, err := templateRefs(`echo {{.a}} {{if eq .b "x"}}{{.c.d}}{{else}}{{$.e}}{{end}} {{with .f}}{{.}}{{end}} {{range .g}}{{end}} {{len .h | printf "%d"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b", "c", "e", "f", "g", "h"}
+	if !slices.Equal(got, want) {
+		t.Errorf("templateRefs() = %v, want %v", got, want)
+	}
+
+	if got, _ := templateRefs("no template here"); got != nil {
+		t.Errorf("templateRefs() = %v, want nil", got)
+	}
+	if _, err := templateRefs("{{.bad"); err == nil {
+		t.Error("expected parse error")
+	}
+}
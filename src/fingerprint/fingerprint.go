@@ -0,0 +1,193 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultDir is the state directory fingerprints are stored under, relative to
+// the working directory.
+const DefaultDir = ".gofer"
+
+// Store keeps one fingerprint per task invocation under Dir/fingerprints.
+type Store struct {
+	Dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Sum hashes the contents of every file matched by the sources and generates
+// globs together with extra (typically the task definition), so any change to
+// inputs, outputs, or the task itself produces a different sum. ok is false
+// when a generates pattern matches no files, i.e. an output is missing.
+func Sum(sources, generates []string, extra string) (sum string, ok bool, err error) {
+	files := make(map[string]bool)
+	for _, pattern := range sources {
+		matches, err := Glob(pattern)
+		if err != nil {
+			return "", false, err
+		}
+		for _, m := range matches {
+			files[m] = true
+		}
+	}
+
+	ok = true
+	for _, pattern := range generates {
+		matches, err := Glob(pattern)
+		if err != nil {
+			return "", false, err
+		}
+		if len(matches) == 0 {
+			ok = false
+		}
+		for _, m := range matches {
+			files[m] = true
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	io.WriteString(h, extra)
+	for _, name := range names {
+		fh, err := hashFile(name)
+		if err != nil {
+			return "", false, err
+		}
+		fmt.Fprintf(h, "\x00%s\x00%s", filepath.ToSlash(name), fh)
+	}
+	return hex.EncodeToString(h.Sum(nil)), ok, nil
+}
+
+func hashFile(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// UpToDate reports whether the stored fingerprint for key equals sum.
+func (s *Store) UpToDate(key, sum string) bool {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(data)) == sum
+}
+
+// Save records sum as the fingerprint for key.
+func (s *Store) Save(key, sum string) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(p, []byte(sum+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write fingerprint: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, "fingerprints", hex.EncodeToString(h[:16]))
+}
+
+// Glob returns the regular files matching pattern. In addition to the
+// path.Match syntax, a "**" segment matches any number of directories.
+func Glob(pattern string) ([]string, error) {
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	segments := strings.Split(pattern, "/")
+	for _, seg := range segments {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+
+	if !strings.ContainsAny(pattern, `*?[\`) {
+		info, err := os.Stat(filepath.FromSlash(pattern))
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, nil
+		}
+		return []string{filepath.FromSlash(pattern)}, nil
+	}
+
+	// Walk from the longest prefix that contains no glob syntax.
+	i := 0
+	for i < len(segments)-1 && !strings.ContainsAny(segments[i], `*?[\`) {
+		i++
+	}
+	root := strings.Join(segments[:i], "/")
+	if root == "" {
+		root = "."
+		if strings.HasPrefix(pattern, "/") {
+			root = "/"
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == DefaultDir && p != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchSegments(segments, strings.Split(path.Clean(filepath.ToSlash(p)), "/")) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
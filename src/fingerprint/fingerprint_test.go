@@ -0,0 +1,138 @@
+package fingerprint
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGlob(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.go":         "",
+		"README.md":       "",
+		"pkg/a.go":        "",
+		"pkg/sub/b.go":    "",
+		"pkg/sub/c.txt":   "",
+		".gofer/state.go": "",
+	})
+	t.Chdir(dir)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"main.go"}},
+		{"main.go", []string{"main.go"}},
+		{"missing.go", nil},
+		{"pkg/*.go", []string{"pkg/a.go"}},
+		{"**/*.go", []string{"main.go", "pkg/a.go", "pkg/sub/b.go"}},
+		{"pkg/**", []string{"pkg/a.go", "pkg/sub/b.go", "pkg/sub/c.txt"}},
+		{"./pkg/**/*.txt", []string{"pkg/sub/c.txt"}},
+		{"nope/**/*.go", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := Glob(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range got {
+				got[i] = filepath.ToSlash(got[i])
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Glob(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Glob(%q) = %v, want %v", tt.pattern, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestGlob_InvalidPattern(t *testing.T) {
+	if _, err := Glob("src/[.go"); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
+
+func TestSum_ChangesWithContent(t *testing.T) {
+	dir := writeTree(t, map[string]string{"in.txt": "one", "out.txt": "built"})
+	t.Chdir(dir)
+
+	before, ok, err := Sum([]string{"in.txt"}, []string{"out.txt"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected generates to be satisfied")
+	}
+
+	if err := os.WriteFile("in.txt", []byte("two"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	after, _, err := Sum([]string{"in.txt"}, []string{"out.txt"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Error("sum should change when a source changes")
+	}
+
+	withExtra, _, _ := Sum([]string{"in.txt"}, []string{"out.txt"}, "task-v2")
+	if withExtra == after {
+		t.Error("sum should change when extra changes")
+	}
+}
+
+func TestSum_MissingGenerates(t *testing.T) {
+	dir := writeTree(t, map[string]string{"in.txt": "one"})
+	t.Chdir(dir)
+
+	_, ok, err := Sum([]string{"in.txt"}, []string{"bin/*"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("expected ok=false when generates matches nothing")
+	}
+}
+
+func TestStore_SaveAndCheck(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if s.UpToDate("build", "abc") {
+		t.Fatal("nothing saved yet, should not be up to date")
+	}
+	if err := s.Save("build", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.UpToDate("build", "abc") {
+		t.Error("expected up to date after save")
+	}
+	if s.UpToDate("build", "def") {
+		t.Error("different sum should not be up to date")
+	}
+	if s.UpToDate("test", "abc") {
+		t.Error("different key should not be up to date")
+	}
+}
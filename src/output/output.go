@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/Azmekk/gofer/config"
 	"github.com/fatih/color"
@@ -97,6 +98,9 @@ var (
 	boldPrint  = color.New(color.Bold).FprintfFunc()
 	greenPrint = color.New(color.FgGreen, color.Bold).FprintfFunc()
 	redPrint   = color.New(color.FgRed, color.Bold).FprintfFunc()
+	cyanPrint  = color.New(color.FgCyan).FprintfFunc()
+	yellowBold = color.New(color.FgYellow, color.Bold).FprintfFunc()
+	grayPrint  = color.New(color.FgHiBlack).FprintfFunc()
 )
 
 // PrintStepStart prints a step start indicator: ▸ label
@@ -114,6 +118,31 @@ func PrintStepFail(w io.Writer, label string, err error) {
 	redPrint(w, "✗ %s: %s\n", label, err)
 }
 
+// PrintStepSkip prints a step skipped indicator: ↷ label (reason)
+func PrintStepSkip(w io.Writer, label string, reason string) {
+	cyanPrint(w, "↷ %s (%s)\n", label, reason)
+}
+
+// PrintStepInterrupted prints a step interrupted indicator: ■ label: interrupted
+func PrintStepInterrupted(w io.Writer, label string) {
+	yellowBold(w, "■ %s: interrupted\n", label)
+}
+
+// PrintStepCancelled prints a step cancelled indicator: ○ label: cancelled
+func PrintStepCancelled(w io.Writer, label string) {
+	grayPrint(w, "○ %s: cancelled\n", label)
+}
+
+// PrintStepWarn prints a step warning indicator for an ignored failure: ⚠ label: error (ignored)
+func PrintStepWarn(w io.Writer, label string, err error) {
+	yellowBold(w, "⚠ %s: %s (ignored)\n", label, err)
+}
+
+// PrintStepRetry prints a retry indicator: ↻ label: retry 2/3 in 1s
+func PrintStepRetry(w io.Writer, label string, attempt, total int, delay time.Duration) {
+	yellowBold(w, "↻ %s: retry %d/%d in %s\n", label, attempt, total, delay)
+}
+
 // LabelColor returns a color from the palette based on index.
 func LabelColor(index int) *color.Color {
 	return labelColors[index%len(labelColors)]
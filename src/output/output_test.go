@@ -2,8 +2,10 @@ package output
 
 import (
 	"bytes"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azmekk/gofer/config"
 	"github.com/fatih/color"
@@ -141,3 +143,43 @@ func TestLabelColor_Cycling(t *testing.T) {
 		t.Error("expected different colors for index 0 and 1")
 	}
 }
+
+func TestPrintStepSkip(t *testing.T) {
+	var buf bytes.Buffer
+	PrintStepSkip(&buf, "build", "up to date")
+	if got, want := buf.String(), "↷ build (up to date)\n"; got != want {
+		t.Errorf("PrintStepSkip() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintStepInterrupted(t *testing.T) {
+	var buf bytes.Buffer
+	PrintStepInterrupted(&buf, "serve")
+	if got, want := buf.String(), "■ serve: interrupted\n"; got != want {
+		t.Errorf("PrintStepInterrupted() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintStepCancelled(t *testing.T) {
+	var buf bytes.Buffer
+	PrintStepCancelled(&buf, "lint")
+	if got, want := buf.String(), "○ lint: cancelled\n"; got != want {
+		t.Errorf("PrintStepCancelled() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintStepRetry(t *testing.T) {
+	var buf bytes.Buffer
+	PrintStepRetry(&buf, "download", 2, 3, 500*time.Millisecond)
+	if got, want := buf.String(), "↻ download: retry 2/3 in 500ms\n"; got != want {
+		t.Errorf("PrintStepRetry() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintStepWarn(t *testing.T) {
+	var buf bytes.Buffer
+	PrintStepWarn(&buf, "cleanup", errors.New("exit status 1"))
+	if got, want := buf.String(), "⚠ cleanup: exit status 1 (ignored)\n"; got != want {
+		t.Errorf("PrintStepWarn() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"sync"
+)
+
+// Redacted replaces secret values in redacted output.
+const Redacted = "***"
+
+// MinSecretLen is the length below which values aren't redacted: hiding
+// every "1" or "yes" would garble output without hiding anything.
+const MinSecretLen = 4
+
+// Secrets is a set of values to hide from output. It is safe for concurrent
+// use, and a nil *Secrets holds nothing.
+type Secrets struct {
+	mu     sync.RWMutex
+	values [][]byte // longest first, so the longest of overlapping secrets wins
+}
+
+// Add adds values to the set, skipping those shorter than MinSecretLen.
+func (s *Secrets) Add(values ...string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Writers keep using the slice they got from list, so build a new one.
+	updated := slices.Clone(s.values)
+	for _, v := range values {
+		if len(v) < MinSecretLen || slices.ContainsFunc(updated, func(b []byte) bool { return string(b) == v }) {
+			continue
+		}
+		updated = append(updated, []byte(v))
+	}
+	slices.SortStableFunc(updated, func(a, b []byte) int { return len(b) - len(a) })
+	s.values = updated
+}
+
+// Redact returns text with every secret replaced by Redacted.
+func (s *Secrets) Redact(text string) string {
+	out, _ := redact([]byte(text), s.list(), true)
+	return string(out)
+}
+
+func (s *Secrets) list() [][]byte {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values
+}
+
+// redact replaces the secrets in data. Unless final is set, it stops at a
+// tail of data that could be the start of a secret and returns it as rest,
+// to be redacted together with what follows it.
+func redact(data []byte, secrets [][]byte, final bool) (out, rest []byte) {
+	if len(secrets) == 0 {
+		return data, nil
+	}
+	start := 0
+	for i := 0; i < len(data); {
+		matched, partial := 0, false
+		for _, s := range secrets {
+			if s[0] != data[i] {
+				continue
+			}
+			if bytes.HasPrefix(data[i:], s) {
+				matched = len(s)
+				break
+			}
+			if !final && bytes.HasPrefix(s, data[i:]) {
+				// A longer secret may still match, so a shorter one
+				// matching in full has to wait for the rest of it.
+				partial = true
+				break
+			}
+		}
+		switch {
+		case matched > 0:
+			out = append(out, data[start:i]...)
+			out = append(out, Redacted...)
+			i += matched
+			start = i
+		case partial:
+			return append(out, data[start:i]...), data[i:]
+		default:
+			i++
+		}
+	}
+	return append(out, data[start:]...), nil
+}
+
+// RedactWriter is an io.Writer that replaces every secret written through it
+// with Redacted, including secrets split across writes. Output that could be
+// the start of a secret is held back until the next write or Flush.
+// Thread-safe.
+type RedactWriter struct {
+	mu      sync.Mutex
+	dest    io.Writer
+	secrets *Secrets
+	pending []byte
+}
+
+// NewRedactWriter creates a RedactWriter hiding secrets from dest. Secrets
+// added to the set later apply from the next write on; with nil secrets
+// everything passes through.
+func NewRedactWriter(dest io.Writer, secrets *Secrets) *RedactWriter {
+	return &RedactWriter{dest: dest, secrets: secrets}
+}
+
+// Write redacts data and writes it to dest, except for a trailing part that
+// could be the start of a secret.
+func (rw *RedactWriter) Write(data []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	out, rest := redact(append(rw.pending, data...), rw.secrets.list(), false)
+	rw.pending = bytes.Clone(rest)
+	if len(out) > 0 {
+		if _, err := rw.dest.Write(out); err != nil {
+			return len(data), err
+		}
+	}
+	return len(data), nil
+}
+
+// Flush redacts and writes any held back output.
+func (rw *RedactWriter) Flush() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if len(rw.pending) == 0 {
+		return nil
+	}
+	out, _ := redact(rw.pending, rw.secrets.list(), true)
+	rw.pending = nil
+	_, err := rw.dest.Write(out)
+	return err
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestRedactWriter(t *testing.T) {
+	secrets := &Secrets{}
+	secrets.Add("hunter2", "s3cr3t-token", "abc", "abcdefgh", "abcd")
+
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"single write", []string{"password is hunter2\n"}, "password is ***\n"},
+		{"split across writes", []string{"token: s3c", "r3t-", "token done\n"}, "token: *** done\n"},
+		{"several secrets", []string{"hunter2 s3cr3t-token hunter2"}, "*** *** ***"},
+		{"prefix that never completes", []string{"hunt", "ing\n"}, "hunting\n"},
+		{"held back until flush", []string{"ends with hunt"}, "ends with hunt"},
+		{"short values are kept", []string{"abc\n"}, "abc\n"},
+		{"longer secret split across writes", []string{"x abcd", "efgh y"}, "x *** y"},
+		{"shorter secret when the longer one doesn't follow", []string{"x abcd", "ef y"}, "x ***ef y"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		rw := NewRedactWriter(&buf, secrets)
+		for _, w := range tt.writes {
+			if n, err := rw.Write([]byte(w)); n != len(w) || err != nil {
+				t.Fatalf("%s: Write = %d, %v", tt.name, n, err)
+			}
+		}
+		if err := rw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRedactWriter_HoldsBackOnlyPossibleSecrets(t *testing.T) {
+	secrets := &Secrets{}
+	secrets.Add("hunter2")
+	var buf bytes.Buffer
+	rw := NewRedactWriter(&buf, secrets)
+	rw.Write([]byte("Password: hun"))
+	if got := buf.String(); got != "Password: " {
+		t.Errorf("before flush: %q, want %q", got, "Password: ")
+	}
+}
+
+func TestRedactWriter_ThroughPrefixWriter(t *testing.T) {
+	secrets := &Secrets{}
+	secrets.Add("line one\nline two")
+	var buf bytes.Buffer
+	pw := NewPrefixWriter(&buf, "job", color.New(color.FgCyan))
+	rw := NewRedactWriter(pw, secrets)
+	rw.Write([]byte("key: line one\nline"))
+	rw.Write([]byte(" two\n"))
+	rw.Flush()
+	pw.Flush()
+	if got := buf.String(); !strings.Contains(got, "key: ***") || strings.Contains(got, "line") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSecrets_Redact(t *testing.T) {
+	var nilSecrets *Secrets
+	nilSecrets.Add("ignored")
+	if got := nilSecrets.Redact("ignored"); got != "ignored" {
+		t.Errorf("nil Secrets redacted %q", got)
+	}
+
+	secrets := &Secrets{}
+	secrets.Add("token", "token-long")
+	if got, want := secrets.Redact("token-long and token"), "*** and ***"; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+}
@@ -4,7 +4,18 @@ import (
 	"bytes"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Azmekk/gofer/config"
+	"github.com/Azmekk/gofer/executor"
 )
 
 //go:embed gofer_schema.json
@@ -34,9 +45,111 @@ func Validate(data []byte) []error {
 	errs = append(errs, checkDuplicateTaskKeys(data)...)
 
 	for tName, tRaw := range tasks {
+		if strings.Contains(tName, ".") {
+			errs = append(errs, fmt.Errorf("task %q: task names cannot contain dots (dots separate include namespaces)", tName))
+		}
 		errs = append(errs, validateTask(tName, tRaw)...)
 	}
 
+	if includesRaw, ok := raw["includes"]; ok {
+		errs = append(errs, validateIncludes(includesRaw)...)
+	}
+
+	if varsRaw, ok := raw["vars"]; ok {
+		errs = append(errs, validateVars("vars", varsRaw)...)
+	}
+
+	if envFileVal, ok := raw["env_file"]; ok {
+		if err := validateEnvFile(envFileVal); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if precRaw, ok := raw["env_precedence"]; ok {
+		if err := validatePrecedence(precRaw); err != nil {
+			errs = append(errs, fmt.Errorf("env_precedence %w", err))
+		}
+	}
+
+	if keysRaw, ok := raw["env_precedence_keys"]; ok {
+		if keys, ok := keysRaw.(map[string]interface{}); !ok {
+			errs = append(errs, fmt.Errorf("env_precedence_keys must be an object"))
+		} else {
+			for _, key := range slices.Sorted(maps.Keys(keys)) {
+				if err := validatePrecedence(keys[key]); err != nil {
+					errs = append(errs, fmt.Errorf("env_precedence_keys %q %w", key, err))
+				}
+			}
+		}
+	}
+
+	if reqRaw, ok := raw["env_required"]; ok {
+		errs = append(errs, validateNameList("env_required", reqRaw)...)
+	}
+
+	if secretRaw, ok := raw["env_secret"]; ok {
+		errs = append(errs, validatePatternList("env_secret", secretRaw)...)
+	}
+
+	if profilesRaw, ok := raw["profiles"]; ok {
+		errs = append(errs, validateProfiles(profilesRaw)...)
+	}
+
+	if escape, ok := raw["escape"]; ok {
+		if s, ok := escape.(string); !ok || (s != config.EscapeNone && s != config.EscapeAuto) {
+			errs = append(errs, fmt.Errorf("escape must be %q or %q", config.EscapeNone, config.EscapeAuto))
+		}
+	}
+
+	return errs
+}
+
+func validateIncludes(raw interface{}) []error {
+	includes, ok := raw.(map[string]interface{})
+	if !ok {
+		return []error{fmt.Errorf("includes must be an object")}
+	}
+
+	var errs []error
+	for ns, target := range includes {
+		if ns == "" || strings.Contains(ns, ".") {
+			errs = append(errs, fmt.Errorf("include %q: namespace must be non-empty and cannot contain dots", ns))
+		}
+		if path, ok := target.(string); !ok || path == "" {
+			errs = append(errs, fmt.Errorf("include %q: must be a non-empty path or URL string", ns))
+		}
+	}
+	return errs
+}
+
+var varNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateVars checks a vars map: names usable as {{.name}}, values that are
+// either a template string or {"sh": "command"}.
+func validateVars(path string, raw interface{}) []error {
+	vars, ok := raw.(map[string]interface{})
+	if !ok {
+		return []error{fmt.Errorf("%s must be an object", path)}
+	}
+
+	var errs []error
+	for name, v := range vars {
+		if !varNameRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("%s %q: name must be letters, digits and underscores, not starting with a digit", path, name))
+		}
+		src, ok := v.(string)
+		if obj, isObj := v.(map[string]interface{}); isObj {
+			src, ok = obj["sh"].(string)
+			ok = ok && len(obj) == 1
+		}
+		if !ok {
+			errs = append(errs, fmt.Errorf(`%s %q: must be a string or {"sh": "command"}`, path, name))
+			continue
+		}
+		if _, err := template.New(name).Funcs(executor.TemplateFuncs()).Parse(src); err != nil {
+			errs = append(errs, fmt.Errorf("%s %q: not a valid template: %w", path, name, err))
+		}
+	}
 	return errs
 }
 
@@ -186,6 +299,18 @@ func validateTask(path string, raw interface{}) []error {
 		errs = append(errs, validateStep(stepPath, s)...)
 	}
 
+	if finallyRaw, ok := task["finally"]; ok {
+		finally, ok := finallyRaw.([]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("task %q: finally must be an array", path))
+		} else {
+			for i, s := range finally {
+				stepPath := fmt.Sprintf("%s.finally[%d]", path, i)
+				errs = append(errs, validateStep(stepPath, s)...)
+			}
+		}
+	}
+
 	if paramsRaw, ok := task["params"]; ok {
 		params, ok := paramsRaw.([]interface{})
 		if !ok {
@@ -194,16 +319,227 @@ func validateTask(path string, raw interface{}) []error {
 			for i, p := range params {
 				paramPath := fmt.Sprintf("%s.params[%d]", path, i)
 				errs = append(errs, validateParam(paramPath, p)...)
+				if m, ok := p.(map[string]interface{}); ok && m["variadic"] == true && i < len(params)-1 {
+					errs = append(errs, fmt.Errorf("param %q: only the last param can be variadic", paramPath))
+				}
 			}
 		}
 	}
 
+	for _, field := range []string{"deps", "sources", "generates"} {
+		if fieldRaw, ok := task[field]; ok {
+			errs = append(errs, validateStringArray(path, field, fieldRaw)...)
+		}
+	}
+
+	if varsRaw, ok := task["vars"]; ok {
+		errs = append(errs, validateVars(fmt.Sprintf("task %q: vars", path), varsRaw)...)
+	}
+
+	if coeRaw, ok := task["continue_on_error"]; ok {
+		if _, ok := coeRaw.(bool); !ok {
+			errs = append(errs, fmt.Errorf("task %q: continue_on_error must be a boolean", path))
+		}
+	}
+
 	if groupRaw, ok := task["group"]; ok {
 		if _, ok := groupRaw.(string); !ok {
 			errs = append(errs, fmt.Errorf("task %q: group must be a string", path))
 		}
 	}
 
+	if dirVal, ok := task["dir"]; ok {
+		if err := validateDir(dirVal); err != nil {
+			errs = append(errs, fmt.Errorf("task %q: %w", path, err))
+		}
+	}
+
+	if envFileVal, ok := task["env_file"]; ok {
+		if err := validateEnvFile(envFileVal); err != nil {
+			errs = append(errs, fmt.Errorf("task %q: %w", path, err))
+		}
+	}
+
+	if envVal, ok := task["env"]; ok {
+		for _, err := range validateEnv(envVal) {
+			errs = append(errs, fmt.Errorf("task %q: %w", path, err))
+		}
+	}
+
+	if reqRaw, ok := task["env_required"]; ok {
+		for _, err := range validateNameList("env_required", reqRaw) {
+			errs = append(errs, fmt.Errorf("task %q: %w", path, err))
+		}
+	}
+
+	if clearRaw, ok := task["env_clear"]; ok {
+		if _, ok := clearRaw.(bool); !ok {
+			errs = append(errs, fmt.Errorf("task %q: env_clear must be a boolean", path))
+		}
+	}
+
+	if allowRaw, ok := task["env_allow"]; ok {
+		for _, err := range validatePatternList("env_allow", allowRaw) {
+			errs = append(errs, fmt.Errorf("task %q: %w", path, err))
+		}
+		if _, isList := allowRaw.([]interface{}); isList && task["env_clear"] != true {
+			errs = append(errs, fmt.Errorf("task %q: env_allow only applies with env_clear", path))
+		}
+	}
+
+	if secretRaw, ok := task["env_secret"]; ok {
+		for _, err := range validatePatternList("env_secret", secretRaw) {
+			errs = append(errs, fmt.Errorf("task %q: %w", path, err))
+		}
+	}
+
+	return errs
+}
+
+// validateDir checks a task or step dir: a string that parses as a template.
+func validateDir(raw interface{}) error {
+	dir, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("dir must be a string")
+	}
+	if _, err := template.New("dir").Funcs(executor.TemplateFuncs()).Parse(dir); err != nil {
+		return fmt.Errorf("dir is not a valid template: %w", err)
+	}
+	return nil
+}
+
+// validatePrecedence checks an env_precedence value. The error completes a
+// sentence starting with the field's name.
+func validatePrecedence(raw interface{}) error {
+	if s, ok := raw.(string); !ok || (s != config.EnvPrecedenceFile && s != config.EnvPrecedenceHost) {
+		return fmt.Errorf("must be %q or %q", config.EnvPrecedenceFile, config.EnvPrecedenceHost)
+	}
+	return nil
+}
+
+// validateNameList checks a list of environment variable names.
+func validateNameList(field string, raw interface{}) []error {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return []error{fmt.Errorf("%s must be an array", field)}
+	}
+	var errs []error
+	for i, item := range items {
+		if name, ok := item.(string); !ok || name == "" || strings.ContainsAny(name, "=\x00") {
+			errs = append(errs, fmt.Errorf("%s[%d] must be a variable name", field, i))
+		}
+	}
+	return errs
+}
+
+// validatePatternList checks a list of variable name patterns in path.Match
+// syntax.
+func validatePatternList(field string, raw interface{}) []error {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return []error{fmt.Errorf("%s must be an array", field)}
+	}
+	var errs []error
+	for i, item := range items {
+		pattern, ok := item.(string)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s[%d] must be a string", field, i))
+		} else if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d] is not a valid pattern: %w", field, i, err))
+		}
+	}
+	return errs
+}
+
+// validateEnvFile checks an env_file: a string or an array of strings.
+func validateEnvFile(raw interface{}) error {
+	switch v := raw.(type) {
+	case string:
+		return nil
+	case []interface{}:
+		for i, item := range v {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("env_file[%d] must be a string", i)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("env_file must be a string or an array of strings")
+}
+
+func validateProfiles(raw interface{}) []error {
+	profiles, ok := raw.(map[string]interface{})
+	if !ok {
+		return []error{fmt.Errorf("profiles must be an object")}
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(profiles)) {
+		profile, ok := profiles[name].(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("profile %q must be an object", name))
+			continue
+		}
+		if name == "" {
+			errs = append(errs, fmt.Errorf("profile names cannot be empty"))
+		}
+		if envFileVal, ok := profile["env_file"]; ok {
+			if err := validateEnvFile(envFileVal); err != nil {
+				errs = append(errs, fmt.Errorf("profile %q: %w", name, err))
+			}
+		}
+		if varsRaw, ok := profile["vars"]; ok {
+			errs = append(errs, validateVars(fmt.Sprintf("profile %q: vars", name), varsRaw)...)
+		}
+		if paramsRaw, ok := profile["params"]; ok {
+			params, ok := paramsRaw.(map[string]interface{})
+			if !ok {
+				errs = append(errs, fmt.Errorf("profile %q: params must be an object", name))
+				continue
+			}
+			for _, param := range slices.Sorted(maps.Keys(params)) {
+				if _, ok := params[param].(string); !ok {
+					errs = append(errs, fmt.Errorf("profile %q: params %q must be a string", name, param))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateEnv checks a task or step env: an object of template strings
+// keyed by environment variable names.
+func validateEnv(raw interface{}) []error {
+	env, ok := raw.(map[string]interface{})
+	if !ok {
+		return []error{fmt.Errorf("env must be an object")}
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			errs = append(errs, fmt.Errorf("env %q: not a valid variable name", name))
+			continue
+		}
+		src, ok := env[name].(string)
+		if !ok {
+			errs = append(errs, fmt.Errorf("env %q must be a string", name))
+		} else if _, err := template.New(name).Funcs(executor.TemplateFuncs()).Parse(src); err != nil {
+			errs = append(errs, fmt.Errorf("env %q: not a valid template: %w", name, err))
+		}
+	}
+	return errs
+}
+
+func validateStringArray(path, field string, raw interface{}) []error {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return []error{fmt.Errorf("task %q: %s must be an array", path, field)}
+	}
+	var errs []error
+	for i, item := range items {
+		if _, ok := item.(string); !ok {
+			errs = append(errs, fmt.Errorf("task %q: %s[%d] must be a string", path, field, i))
+		}
+	}
 	return errs
 }
 
@@ -215,7 +551,82 @@ func validateParam(path string, raw interface{}) []error {
 	if _, ok := param["name"]; !ok {
 		return []error{fmt.Errorf("param %q: missing required field: name", path)}
 	}
-	return nil
+
+	var errs []error
+	var p config.Param
+	valid := true
+
+	if descRaw, ok := param["desc"]; ok {
+		if _, ok := descRaw.(string); !ok {
+			errs = append(errs, fmt.Errorf("param %q: desc must be a string", path))
+		}
+	}
+
+	if variadicRaw, ok := param["variadic"]; ok {
+		if _, ok := variadicRaw.(bool); !ok {
+			errs = append(errs, fmt.Errorf("param %q: variadic must be a boolean", path))
+		}
+	}
+
+	if secretRaw, ok := param["secret"]; ok {
+		if _, ok := secretRaw.(bool); !ok {
+			errs = append(errs, fmt.Errorf("param %q: secret must be a boolean", path))
+		}
+	}
+
+	if typeRaw, ok := param["type"]; ok {
+		t, ok := typeRaw.(string)
+		if !ok || !slices.Contains(config.ParamTypes, t) {
+			errs = append(errs, fmt.Errorf("param %q: type must be one of %s", path, strings.Join(config.ParamTypes, ", ")))
+			valid = false
+		}
+		p.Type = t
+	}
+
+	if choicesRaw, ok := param["choices"]; ok {
+		choices, ok := choicesRaw.([]interface{})
+		if !ok || len(choices) == 0 {
+			errs = append(errs, fmt.Errorf("param %q: choices must be a non-empty array of strings", path))
+			valid = false
+		}
+		for _, c := range choices {
+			cs, ok := c.(string)
+			if !ok {
+				errs = append(errs, fmt.Errorf("param %q: choices must be a non-empty array of strings", path))
+				valid = false
+				break
+			}
+			p.Choices = append(p.Choices, cs)
+		}
+	} else if p.Type == "enum" {
+		errs = append(errs, fmt.Errorf("param %q: enum params require choices", path))
+		valid = false
+	}
+
+	if patternRaw, ok := param["pattern"]; ok {
+		pattern, ok := patternRaw.(string)
+		if !ok {
+			errs = append(errs, fmt.Errorf("param %q: pattern must be a string", path))
+			valid = false
+		} else if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("param %q: invalid pattern: %w", path, err))
+			valid = false
+		}
+		p.Pattern = pattern
+	}
+
+	if defRaw, ok := param["default"]; ok {
+		def, ok := defRaw.(string)
+		if !ok {
+			errs = append(errs, fmt.Errorf("param %q: default must be a string", path))
+		} else if valid {
+			if err := p.ValidateDefault(def); err != nil {
+				errs = append(errs, fmt.Errorf("param %q: default %w", path, errors.Unwrap(err)))
+			}
+		}
+	}
+
+	return errs
 }
 
 func validateStep(path string, raw interface{}) []error {
@@ -259,6 +670,99 @@ func validateStep(path string, raw interface{}) []error {
 		}
 	}
 
+	if ffVal, ok := step["fail_fast"]; ok {
+		if _, ok := ffVal.(bool); !ok {
+			errs = append(errs, fmt.Errorf("step %q: fail_fast must be a boolean", path))
+		} else if !hasConcurrent {
+			errs = append(errs, fmt.Errorf("step %q: fail_fast is only valid on concurrent steps", path))
+		}
+	}
+
+	if mpVal, ok := step["max_parallel"]; ok {
+		if n, ok := mpVal.(float64); !ok || n < 1 || n != float64(int(n)) {
+			errs = append(errs, fmt.Errorf("step %q: max_parallel must be a positive integer", path))
+		} else if !hasConcurrent {
+			errs = append(errs, fmt.Errorf("step %q: max_parallel is only valid on concurrent steps", path))
+		}
+	}
+
+	if timeoutVal, ok := step["timeout"]; ok {
+		if err := validateDuration(timeoutVal); err != nil {
+			errs = append(errs, fmt.Errorf("step %q: timeout %w", path, err))
+		}
+	}
+
+	if retryVal, ok := step["retry"]; ok {
+		errs = append(errs, validateRetry(path, retryVal)...)
+	}
+
+	if ieVal, ok := step["ignore_error"]; ok {
+		if _, ok := ieVal.(bool); !ok {
+			errs = append(errs, fmt.Errorf("step %q: ignore_error must be a boolean", path))
+		}
+	}
+
+	for _, field := range []string{"capture", "capture_exit"} {
+		if v, ok := step[field]; ok {
+			if name, ok := v.(string); !ok || !varNameRe.MatchString(name) {
+				errs = append(errs, fmt.Errorf("step %q: %s must be a name made of letters, digits and underscores", path, field))
+			} else if !hasCmd {
+				errs = append(errs, fmt.Errorf("step %q: %s is only valid on cmd steps", path, field))
+			}
+		}
+	}
+
+	if silentVal, ok := step["silent"]; ok {
+		if _, ok := silentVal.(bool); !ok {
+			errs = append(errs, fmt.Errorf("step %q: silent must be a boolean", path))
+		} else if !hasCmd {
+			errs = append(errs, fmt.Errorf("step %q: silent is only valid on cmd steps", path))
+		}
+	}
+
+	if withVal, ok := step["with"]; ok {
+		if with, ok := withVal.(map[string]interface{}); !ok {
+			errs = append(errs, fmt.Errorf("step %q: with must be an object", path))
+		} else {
+			if !hasRef {
+				errs = append(errs, fmt.Errorf("step %q: with is only valid on ref steps", path))
+			}
+			for name, v := range with {
+				if _, ok := v.(string); !ok {
+					errs = append(errs, fmt.Errorf("step %q: with %q must be a string", path, name))
+				}
+			}
+		}
+	}
+
+	if ifVal, ok := step["if"]; ok {
+		if ifStr, ok := ifVal.(string); !ok {
+			errs = append(errs, fmt.Errorf("step %q: if must be a string", path))
+		} else if _, err := template.New("if").Funcs(executor.TemplateFuncs()).Parse(ifStr); err != nil {
+			errs = append(errs, fmt.Errorf("step %q: if is not a valid template: %w", path, err))
+		}
+	}
+
+	if dirVal, ok := step["dir"]; ok {
+		if err := validateDir(dirVal); err != nil {
+			errs = append(errs, fmt.Errorf("step %q: %w", path, err))
+		} else if hasRef {
+			errs = append(errs, fmt.Errorf("step %q: dir is not valid on ref steps; set it on the referenced task", path))
+		}
+	}
+
+	if envVal, ok := step["env"]; ok {
+		for _, err := range validateEnv(envVal) {
+			errs = append(errs, fmt.Errorf("step %q: %w", path, err))
+		}
+	}
+
+	if ifCmdVal, ok := step["if_cmd"]; ok {
+		if _, ok := ifCmdVal.(string); !ok {
+			errs = append(errs, fmt.Errorf("step %q: if_cmd must be a string", path))
+		}
+	}
+
 	if osVal, ok := step["os"]; ok {
 		if osStr, ok := osVal.(string); ok {
 			validOS := map[string]bool{"*": true, "": true, "linux": true, "darwin": true, "windows": true}
@@ -270,3 +774,43 @@ func validateStep(path string, raw interface{}) []error {
 
 	return errs
 }
+
+// validateDuration checks that raw is a positive Go duration string such as
+// "30s" or "1m30s".
+func validateDuration(raw interface{}) error {
+	str, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("must be a duration string")
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return fmt.Errorf("must be a duration string like \"30s\" (got %q)", str)
+	}
+	if d <= 0 {
+		return fmt.Errorf("must be positive (got %q)", str)
+	}
+	return nil
+}
+
+func validateRetry(path string, raw interface{}) []error {
+	retry, ok := raw.(map[string]interface{})
+	if !ok {
+		return []error{fmt.Errorf("step %q: retry must be an object", path)}
+	}
+
+	var errs []error
+	if n, ok := retry["count"].(float64); !ok || n < 1 || n != float64(int(n)) {
+		errs = append(errs, fmt.Errorf("step %q: retry.count must be a positive integer", path))
+	}
+	if delay, ok := retry["delay"]; ok {
+		if err := validateDuration(delay); err != nil {
+			errs = append(errs, fmt.Errorf("step %q: retry.delay %w", path, err))
+		}
+	}
+	if backoff, ok := retry["backoff"]; ok {
+		if n, ok := backoff.(float64); !ok || n < 1 {
+			errs = append(errs, fmt.Errorf("step %q: retry.backoff must be a number >= 1", path))
+		}
+	}
+	return errs
+}
@@ -101,6 +101,358 @@ func TestValidate(t *testing.T) {
 			json:     `{"tasks":{"t":{"desc":"d","params":[{"name":"x"}],"steps":[{"cmd":"echo"}]}}}`,
 			wantErrs: 0,
 		},
+		{
+			name:     "valid deps",
+			json:     `{"tasks":{"t":{"desc":"d","deps":["a","b"],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "deps not an array",
+			json:      `{"tasks":{"t":{"desc":"d","deps":"a","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "deps must be an array",
+		},
+		{
+			name:      "deps entry not a string",
+			json:      `{"tasks":{"t":{"desc":"d","deps":[1],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "deps[0] must be a string",
+		},
+		{
+			name:     "valid sources and generates",
+			json:     `{"tasks":{"t":{"desc":"d","sources":["src/**/*.go"],"generates":["bin/app"],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "generates not an array",
+			json:      `{"tasks":{"t":{"desc":"d","generates":"bin/app","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "generates must be an array",
+		},
+		{
+			name:     "valid includes",
+			json:     `{"includes":{"backend":"./backend/gofer.json"},"tasks":{"t":{"desc":"d","steps":[{"ref":"backend.build"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "include namespace with dot",
+			json:      `{"includes":{"a.b":"x.json"},"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "cannot contain dots",
+		},
+		{
+			name:      "include path not a string",
+			json:      `{"includes":{"a":1},"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "non-empty path or URL",
+		},
+		{
+			name:      "task name with dot",
+			json:      `{"tasks":{"a.b":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "task names cannot contain dots",
+		},
+		{
+			name:     "fail_fast on concurrent",
+			json:     `{"tasks":{"t":{"desc":"d","steps":[{"fail_fast":true,"concurrent":[{"cmd":"echo a"}]}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "fail_fast on cmd step",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"fail_fast":true,"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "fail_fast is only valid on concurrent steps",
+		},
+		{
+			name:     "max_parallel on concurrent",
+			json:     `{"tasks":{"t":{"desc":"d","steps":[{"max_parallel":2,"concurrent":[{"cmd":"echo a"}]}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "max_parallel zero",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"max_parallel":0,"concurrent":[{"cmd":"echo a"}]}]}}}`,
+			wantErrs:  1,
+			wantMatch: "max_parallel must be a positive integer",
+		},
+		{
+			name:      "max_parallel on ref step",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"max_parallel":2,"ref":"x"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "max_parallel is only valid on concurrent steps",
+		},
+		{
+			name:     "valid timeout and retry",
+			json:     `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","timeout":"30s","retry":{"count":3,"delay":"1s","backoff":2}}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "invalid timeout",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","timeout":"soon"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "timeout must be a duration string",
+		},
+		{
+			name:      "retry missing count",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","retry":{"delay":"1s"}}]}}}`,
+			wantErrs:  1,
+			wantMatch: "retry.count must be a positive integer",
+		},
+		{
+			name:      "retry backoff below one",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","retry":{"count":1,"backoff":0.5}}]}}}`,
+			wantErrs:  1,
+			wantMatch: "retry.backoff must be a number >= 1",
+		},
+		{
+			name:     "ignore_error and continue_on_error",
+			json:     `{"tasks":{"t":{"desc":"d","continue_on_error":true,"steps":[{"cmd":"echo","ignore_error":true}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "ignore_error not a boolean",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","ignore_error":"yes"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "ignore_error must be a boolean",
+		},
+		{
+			name:     "valid finally steps",
+			json:     `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}],"finally":[{"cmd":"echo bye","ignore_error":true}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "finally not an array",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}],"finally":"echo"}}}`,
+			wantErrs:  1,
+			wantMatch: "finally must be an array",
+		},
+		{
+			name:      "invalid finally step",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}],"finally":[{"cmd":"echo","ref":"x"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "t.finally[0]",
+		},
+		{
+			name:     "valid if and if_cmd",
+			json:     `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","if":"{{ eq .mode \"release\" }}","if_cmd":"test -f x"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "if is not a valid template",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","if":"{{ eq .mode"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "if is not a valid template",
+		},
+		{
+			name:      "if_cmd not a string",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","if_cmd":true}]}}}`,
+			wantErrs:  1,
+			wantMatch: "if_cmd must be a string",
+		},
+		{
+			name:     "valid typed params",
+			json:     `{"tasks":{"t":{"desc":"d","params":[{"name":"env","type":"enum","choices":["dev","prod"],"default":"dev","desc":"target"},{"name":"n","type":"int","default":"3"},{"name":"tag","pattern":"v[0-9]+","default":"v1"},{"name":"src","type":"path","default":"does/not/exist"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "unknown param type",
+			json:      `{"tasks":{"t":{"desc":"d","params":[{"name":"n","type":"float"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "type must be one of",
+		},
+		{
+			name:      "enum param without choices",
+			json:      `{"tasks":{"t":{"desc":"d","params":[{"name":"env","type":"enum"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "enum params require choices",
+		},
+		{
+			name:      "invalid param pattern",
+			json:      `{"tasks":{"t":{"desc":"d","params":[{"name":"tag","pattern":"v[0-9"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "invalid pattern",
+		},
+		{
+			name:      "default outside choices",
+			json:      `{"tasks":{"t":{"desc":"d","params":[{"name":"env","type":"enum","choices":["dev","prod"],"default":"prodd"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: `default "prodd" is not one of dev, prod`,
+		},
+		{
+			name:      "default not an int",
+			json:      `{"tasks":{"t":{"desc":"d","params":[{"name":"n","type":"int","default":"three"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "is not an integer",
+		},
+		{
+			name:      "secret not a boolean",
+			json:      `{"tasks":{"t":{"desc":"d","params":[{"name":"token","secret":"yes"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "secret must be a boolean",
+		},
+		{
+			name:     "valid with on ref",
+			json:     `{"tasks":{"t":{"desc":"d","steps":[{"ref":"c","with":{"output":"bin/{{.name}}"}}]},"c":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "with on cmd step",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","with":{"a":"b"}}]}}}`,
+			wantErrs:  1,
+			wantMatch: "with is only valid on ref steps",
+		},
+		{
+			name:      "with value not a string",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"ref":"c","with":{"n":3}}]},"c":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: `with "n" must be a string`,
+		},
+		{
+			name:     "valid variadic param",
+			json:     `{"tasks":{"t":{"desc":"d","params":[{"name":"pkg"},{"name":"files","variadic":true}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "variadic param not last",
+			json:      `{"tasks":{"t":{"desc":"d","params":[{"name":"files","variadic":true},{"name":"pkg"}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "only the last param can be variadic",
+		},
+		{
+			name:     "valid vars",
+			json:     `{"vars":{"sha":{"sh":"git rev-parse --short HEAD"},"name":"app"},"tasks":{"t":{"desc":"d","vars":{"image":"{{.name}}:{{.sha}}"},"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "var with unknown object shape",
+			json:      `{"vars":{"sha":{"cmd":"git"}},"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: `must be a string or {"sh": "command"}`,
+		},
+		{
+			name:      "invalid var name",
+			json:      `{"tasks":{"t":{"desc":"d","vars":{"my-var":"x"},"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "name must be letters, digits and underscores",
+		},
+		{
+			name:      "var is not a valid template",
+			json:      `{"vars":{"v":"{{.x"},"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "not a valid template",
+		},
+		{
+			name:     "valid capture",
+			json:     `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"git describe","capture":"version","capture_exit":"code","silent":true}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "capture on ref step",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"ref":"c","capture":"out"}]},"c":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "capture is only valid on cmd steps",
+		},
+		{
+			name:      "invalid capture name",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","capture":"my-out"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "capture must be a name",
+		},
+		{
+			name:     "valid escape",
+			json:     `{"escape":"auto","tasks":{"t":{"desc":"d","steps":[{"cmd":"echo {{ .x | raw }}"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "invalid escape",
+			json:      `{"escape":"always","tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "escape must be",
+		},
+		{
+			name:     "valid dir",
+			json:     `{"tasks":{"t":{"desc":"d","dir":"{{.app}}","steps":[{"cmd":"ls","dir":"src"},{"concurrent":[{"cmd":"ls"}],"dir":"docs"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "dir on ref step",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"ref":"c","dir":"x"}]},"c":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "dir is not valid on ref steps",
+		},
+		{
+			name:      "dir not a string",
+			json:      `{"tasks":{"t":{"desc":"d","dir":1,"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "dir must be a string",
+		},
+		{
+			name:     "valid env",
+			json:     `{"tasks":{"t":{"desc":"d","env_file":".env.build","env":{"NODE_ENV":"{{.mode}}"},"steps":[{"cmd":"echo","env":{"DEBUG":"1"}}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "env value not a string",
+			json:      `{"tasks":{"t":{"desc":"d","env":{"PORT":8080},"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: `env "PORT" must be a string`,
+		},
+		{
+			name:      "invalid env name",
+			json:      `{"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo","env":{"A=B":"x"}}]}}}`,
+			wantErrs:  1,
+			wantMatch: "not a valid variable name",
+		},
+		{
+			name:     "valid profiles",
+			json:     `{"env_file":[".env.gofer","?.env.local"],"profiles":{"prod":{"env_file":".env.prod","vars":{"tier":"paid"},"params":{"replicas":"3"}}},"tasks":{"t":{"desc":"d","env_file":["?.env.t"],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "env_file list with a number",
+			json:      `{"env_file":[".env",1],"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "env_file[1] must be a string",
+		},
+		{
+			name:      "profile param not a string",
+			json:      `{"profiles":{"prod":{"params":{"replicas":3}}},"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: `params "replicas" must be a string`,
+		},
+		{
+			name:     "valid env precedence",
+			json:     `{"env_precedence":"host","env_precedence_keys":{"PATH":"file"},"env_required":["API_TOKEN"],"tasks":{"t":{"desc":"d","env_required":["DB_URL"],"env_clear":true,"env_allow":["PATH","LC_*"],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "invalid env precedence",
+			json:      `{"env_precedence":"env","env_precedence_keys":{"PATH":"shell"},"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  2,
+			wantMatch: `must be "file" or "host"`,
+		},
+		{
+			name:      "env_allow without env_clear",
+			json:      `{"tasks":{"t":{"desc":"d","env_allow":["PATH"],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "env_allow only applies with env_clear",
+		},
+		{
+			name:      "env_required not names",
+			json:      `{"env_required":["OK",""],"tasks":{"t":{"desc":"d","steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  1,
+			wantMatch: "env_required[1] must be a variable name",
+		},
+		{
+			name:     "valid env_secret",
+			json:     `{"env_secret":["*_TOKEN"],"tasks":{"t":{"desc":"d","env_secret":["DB_PASSWORD"],"params":[{"name":"key","secret":true}],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs: 0,
+		},
+		{
+			name:      "invalid env_secret",
+			json:      `{"env_secret":"API_TOKEN","tasks":{"t":{"desc":"d","env_secret":["[A-"],"steps":[{"cmd":"echo"}]}}}`,
+			wantErrs:  2,
+			wantMatch: "env_secret must be an array",
+		},
 		{
 			name:      "invalid json",
 			json:      `{not json}`,